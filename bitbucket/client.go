@@ -2,6 +2,7 @@ package bitbucket
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -9,49 +10,132 @@ import (
 	"log"
 	"mime/multipart"
 	"net/http"
+	"net/url"
 	"os"
 	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/testOrgNataichi/terraform-provider-bitbucketserver/bitbucket/redact"
+	"github.com/testOrgNataichi/terraform-provider-bitbucketserver/bitbucket/transport"
 )
 
-// Error represents a error from the bitbucket api.
-type Error struct {
-	Errors []struct {
-		Context   string `json:"context,omitempty"`
-		Message   string `json:"message,omitempty"`
-		Exception string `json:"exceptionName,omitempty"`
-	} `json:"errors,omitempty"`
-	StatusCode int
-	Endpoint   string
+type BitbucketClient struct {
+	Server     string
+	Username   string
+	Password   string
+	Token      string
+	HTTPClient *http.Client
+	// PageLimit is the page size requested from paged endpoints, 0 uses the server default.
+	PageLimit int
+	// Limiters throttle the requests sent to each API. They are enforced per attempt by a
+	// transport.LimitTransport in the transport chain of HTTPClient.
+	Limiters RequestLimiters
+	// Context is the parent context of all requests, cancelling it aborts requests in flight.
+	Context context.Context
+	// RequestTimeout bounds the duration of a request including its retries, 0 means no timeout.
+	RequestTimeout time.Duration
 }
 
-func (e Error) Error() string {
+// RequestLimiters holds a separate request budget for each API served by Bitbucket, so plugin
+// endpoints can be throttled harder than the core API. A nil limiter does not limit.
+type RequestLimiters struct {
+	Core     *transport.Limiter
+	Workzone *transport.Limiter
+	UPM      *transport.Limiter
+}
 
-	var errorMessages = ""
-	if e.Errors != nil {
-		for _, err := range e.Errors {
-			errorMessages += err.Message + "\n"
-		}
+// limiter returns the limiter of the API the endpoint belongs to.
+func (c *BitbucketClient) limiter(endpoint string) *transport.Limiter {
+	switch {
+	case strings.HasPrefix(endpoint, "/rest/workzoneresource/"):
+		return c.Limiters.Workzone
+	case strings.HasPrefix(endpoint, "/rest/plugins/"):
+		return c.Limiters.UPM
+	default:
+		return c.Limiters.Core
 	}
+}
 
-	return fmt.Sprintf("API Error: %d %s %s", e.StatusCode, e.Endpoint, errorMessages)
+// authorize adds the configured credentials to the request. A personal access token
+// takes precedence over basic auth.
+func (c *BitbucketClient) authorize(req *http.Request) {
+	if c.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.Token)
+	} else {
+		req.SetBasicAuth(c.Username, c.Password)
+	}
 }
 
-type BitbucketClient struct {
-	Server     string
-	Username   string
-	Password   string
-	HTTPClient *http.Client
+// upmEndpoint appends the os_authType hint UPM expects for basic auth requests.
+// Token authenticated requests are sent as-is.
+func (c *BitbucketClient) upmEndpoint(endpoint string) string {
+	if c.Token != "" {
+		return endpoint
+	}
+	if strings.Contains(endpoint, "?") {
+		return endpoint + "&os_authType=basic"
+	}
+	return endpoint + "?os_authType=basic"
+}
+
+// send executes the request and buffers the response body, so the connection is returned to the
+// pool even when callers never read or close the body.
+func (c *BitbucketClient) send(req *http.Request, endpoint string) (*http.Response, error) {
+	ctx := c.Context
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	// the limiter is acquired by the transport for every attempt, including retries
+	ctx = transport.WithLimiter(ctx, c.limiter(endpoint))
+
+	if c.RequestTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.RequestTimeout)
+		defer cancel()
+	}
+	req = req.WithContext(ctx)
+
+	resp, err := c.HTTPClient.Do(req)
+	log.Printf("[DEBUG] Resp: %s Err: %v", redact.Response(resp), err)
+	if err != nil {
+		return resp, err
+	}
+
+	body, err := ioutil.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = ioutil.NopCloser(bytes.NewReader(body))
+
+	if resp.StatusCode >= 400 || resp.StatusCode < 200 {
+		apiError := Error{
+			StatusCode: resp.StatusCode,
+			Endpoint:   endpoint,
+		}
+
+		log.Printf("[DEBUG] Resp Body: %s", redact.Body(body))
+
+		_ = json.Unmarshal(body, &apiError)
+		return resp, error(apiError)
+	}
+
+	return resp, nil
 }
 
 func (c *BitbucketClient) Do(method, endpoint string, payload *bytes.Buffer, contentType string) (*http.Response, error) {
 
 	absoluteendpoint := c.Server + endpoint
-	log.Printf("[DEBUG] Sending request to %s %s", method, absoluteendpoint)
+	log.Printf("[DEBUG] Sending request to %s %s", method, redact.URL(absoluteendpoint))
 
 	var bodyreader io.Reader
 
 	if payload != nil {
-		log.Printf("[DEBUG] With payload %s", payload.String())
+		log.Printf("[DEBUG] With payload %s", redact.Body(payload.Bytes()))
 		bodyreader = payload
 	}
 
@@ -60,7 +144,7 @@ func (c *BitbucketClient) Do(method, endpoint string, payload *bytes.Buffer, con
 		return nil, err
 	}
 
-	req.SetBasicAuth(c.Username, c.Password)
+	c.authorize(req)
 	req.Header.Add("X-Atlassian-Token", "no-check")
 
 	if payload != nil {
@@ -72,34 +156,13 @@ func (c *BitbucketClient) Do(method, endpoint string, payload *bytes.Buffer, con
 		}
 	}
 
-	req.Close = true
-
-	resp, err := c.HTTPClient.Do(req)
-	log.Printf("[DEBUG] Resp: %v Err: %v", resp, err)
-	if resp != nil && (resp.StatusCode >= 400 || resp.StatusCode < 200) {
-		apiError := Error{
-			StatusCode: resp.StatusCode,
-			Endpoint:   endpoint,
-		}
-
-		body, err := ioutil.ReadAll(resp.Body)
-		if err != nil {
-			return nil, err
-		}
-
-		log.Printf("[DEBUG] Resp Body: %s", string(body))
-
-		_ = json.Unmarshal(body, &apiError)
-		return resp, error(apiError)
-
-	}
-	return resp, err
+	return c.send(req, endpoint)
 }
 
 // Creates a new file upload http request with optional extra params
 func (c *BitbucketClient) PostFileUpload(endpoint string, params map[string]string, paramName, path string) (*http.Response, error) {
 	absoluteendpoint := c.Server + endpoint
-	log.Printf("[DEBUG] Sending request to POST %s", absoluteendpoint)
+	log.Printf("[DEBUG] Sending request to POST %s", redact.URL(absoluteendpoint))
 
 	file, err := os.Open(path)
 	if err != nil {
@@ -114,6 +177,9 @@ func (c *BitbucketClient) PostFileUpload(endpoint string, params map[string]stri
 		return nil, err
 	}
 	_, err = io.Copy(part, file)
+	if err != nil {
+		return nil, err
+	}
 
 	for key, val := range params {
 		_ = writer.WriteField(key, val)
@@ -130,31 +196,9 @@ func (c *BitbucketClient) PostFileUpload(endpoint string, params map[string]stri
 
 	req.Header.Set("Content-Type", writer.FormDataContentType())
 
-	req.SetBasicAuth(c.Username, c.Password)
+	c.authorize(req)
 	req.Header.Add("X-Atlassian-Token", "no-check")
-	req.Close = true
-
-	resp, err := c.HTTPClient.Do(req)
-	log.Printf("[DEBUG] Resp: %v Err: %v", resp, err)
-	if resp != nil && (resp.StatusCode >= 400 || resp.StatusCode < 200) {
-		apiError := Error{
-			StatusCode: resp.StatusCode,
-			Endpoint:   endpoint,
-		}
-
-		body, err := ioutil.ReadAll(resp.Body)
-		if err != nil {
-			return nil, err
-		}
-
-		log.Printf("[DEBUG] Resp Body: %s", string(body))
-
-		_ = json.Unmarshal(body, &apiError)
-		return resp, error(apiError)
-
-	}
-
-	return resp, err
+	return c.send(req, endpoint)
 }
 
 type PluginInstallPayload struct {
@@ -166,7 +210,7 @@ func (c *BitbucketClient) InstallPluginWithUri(endpoint string, uri string, plug
 	// The method implements this functionality
 	// https://developer.atlassian.com/platform/marketplace/registering-apps/#installing-an-app-using-the-rest-api
 	absoluteendpoint := c.Server + endpoint
-	log.Printf("[DEBUG] Sending request to POST %s", absoluteendpoint)
+	log.Printf("[DEBUG] Sending request to POST %s", redact.URL(absoluteendpoint))
 
 	installPayload := PluginInstallPayload{
 		PluginURI:  uri,
@@ -185,32 +229,10 @@ func (c *BitbucketClient) InstallPluginWithUri(endpoint string, uri string, plug
 
 	req.Header.Set("Content-Type", "application/vnd.atl.plugins.install.uri+json")
 
-	req.SetBasicAuth(c.Username, c.Password)
+	c.authorize(req)
 	req.Header.Add("X-Atlassian-Token", "no-check")
 	req.Header.Add("Accept", "application/json")
-	req.Close = true
-
-	resp, err := c.HTTPClient.Do(req)
-	log.Printf("[DEBUG] Resp: %v Err: %v", resp, err)
-	if resp != nil && (resp.StatusCode >= 400 || resp.StatusCode < 200) {
-		apiError := Error{
-			StatusCode: resp.StatusCode,
-			Endpoint:   endpoint,
-		}
-
-		body, err := ioutil.ReadAll(resp.Body)
-		if err != nil {
-			return nil, err
-		}
-
-		log.Printf("[DEBUG] Resp Body: %s", string(body))
-
-		_ = json.Unmarshal(body, &apiError)
-		return resp, error(apiError)
-
-	}
-
-	return resp, err
+	return c.send(req, endpoint)
 }
 
 func (c *BitbucketClient) Get(endpoint string) (*http.Response, error) {
@@ -237,3 +259,66 @@ func (c *BitbucketClient) Delete(endpoint string) (*http.Response, error) {
 func (c *BitbucketClient) DeleteWithBody(endpoint string, jsonpayload *bytes.Buffer) (*http.Response, error) {
 	return c.Do("DELETE", endpoint, jsonpayload, "application/json")
 }
+
+// PagedResponse is a single page of a paged Bitbucket API response.
+type PagedResponse struct {
+	Values        json.RawMessage `json:"values,omitempty"`
+	Size          int             `json:"size,omitempty"`
+	Limit         int             `json:"limit,omitempty"`
+	IsLastPage    bool            `json:"isLastPage,omitempty"`
+	Start         int             `json:"start,omitempty"`
+	NextPageStart int             `json:"nextPageStart,omitempty"`
+}
+
+// GetAllPages fetches every page of a paged endpoint and appends the values to the slice
+// pointed to by values. The query parameters are sent with every page, the page size defaults
+// to PageLimit unless params sets a limit.
+func (c *BitbucketClient) GetAllPages(endpoint string, params url.Values, values interface{}) error {
+	slice := reflect.ValueOf(values)
+	if slice.Kind() != reflect.Ptr || slice.Elem().Kind() != reflect.Slice {
+		return fmt.Errorf("values must be a pointer to a slice, got %T", values)
+	}
+	slice = slice.Elem()
+
+	query := url.Values{}
+	for key, value := range params {
+		query[key] = value
+	}
+	if query.Get("limit") == "" && c.PageLimit > 0 {
+		query.Set("limit", strconv.Itoa(c.PageLimit))
+	}
+
+	for {
+		resourceURL := endpoint
+		if len(query) > 0 {
+			resourceURL += "?" + query.Encode()
+		}
+
+		resp, err := c.Get(resourceURL)
+		if err != nil {
+			return err
+		}
+
+		var page PagedResponse
+		err = json.NewDecoder(resp.Body).Decode(&page)
+		_ = resp.Body.Close()
+		if err != nil {
+			return err
+		}
+
+		if len(page.Values) > 0 {
+			pageValues := reflect.New(slice.Type())
+			err = json.Unmarshal(page.Values, pageValues.Interface())
+			if err != nil {
+				return err
+			}
+			slice.Set(reflect.AppendSlice(slice, pageValues.Elem()))
+		}
+
+		if page.IsLastPage || page.NextPageStart <= page.Start {
+			return nil
+		}
+
+		query.Set("start", strconv.Itoa(page.NextPageStart))
+	}
+}
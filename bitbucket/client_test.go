@@ -0,0 +1,212 @@
+package bitbucket
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/testOrgNataichi/terraform-provider-bitbucketserver/bitbucket/transport"
+)
+
+func TestBitbucketClient_TokenAuth(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if got := r.Header.Get("Authorization"); got != "Bearer my-token" {
+			t.Errorf("unexpected Authorization header %q", got)
+		}
+		if got := r.URL.Query().Get("os_authType"); got != "" {
+			t.Errorf("unexpected os_authType %q", got)
+		}
+	}))
+	defer server.Close()
+
+	client := &BitbucketClient{
+		Server:     server.URL,
+		Token:      "my-token",
+		HTTPClient: server.Client(),
+	}
+
+	if _, err := client.Get(client.upmEndpoint("/rest/plugins/1.0/")); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+}
+
+func TestBitbucketClient_BasicAuth(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		username, password, ok := r.BasicAuth()
+		if !ok || username != "admin" || password != "secret" {
+			t.Errorf("unexpected basic auth %q/%q", username, password)
+		}
+		if got := r.URL.Query().Get("os_authType"); got != "basic" {
+			t.Errorf("unexpected os_authType %q", got)
+		}
+	}))
+	defer server.Close()
+
+	client := &BitbucketClient{
+		Server:     server.URL,
+		Username:   "admin",
+		Password:   "secret",
+		HTTPClient: server.Client(),
+	}
+
+	if _, err := client.Get(client.upmEndpoint("/rest/plugins/1.0/")); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+}
+
+func TestBitbucketClient_GetAllPages(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		if query.Get("filter") != "stash" || query.Get("limit") != "2" {
+			t.Errorf("query parameters not preserved: %s", r.URL.RawQuery)
+		}
+
+		switch query.Get("start") {
+		case "":
+			fmt.Fprint(w, `{"values": [{"name": "stash-users"}, {"name": "stash-admins"}], "isLastPage": false, "start": 0, "nextPageStart": 2}`)
+		case "2":
+			fmt.Fprint(w, `{"values": [{"name": "stash-devs"}], "isLastPage": true, "start": 2}`)
+		default:
+			t.Errorf("unexpected start %s", query.Get("start"))
+		}
+	}))
+	defer server.Close()
+
+	client := &BitbucketClient{Server: server.URL, Token: "token", HTTPClient: server.Client(), PageLimit: 2}
+
+	var values []struct {
+		Name string `json:"name"`
+	}
+	err := client.GetAllPages("/rest/api/1.0/admin/groups", url.Values{"filter": []string{"stash"}}, &values)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	var names []string
+	for _, value := range values {
+		names = append(names, value.Name)
+	}
+
+	expected := []string{"stash-users", "stash-admins", "stash-devs"}
+	if !reflect.DeepEqual(names, expected) {
+		t.Fatalf("expected %v, got %v", expected, names)
+	}
+}
+
+func TestBitbucketClient_ReusesConnections(t *testing.T) {
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"name": "stash-users"}`)
+	}))
+
+	connections := 0
+	server.Config.ConnState = func(conn net.Conn, state http.ConnState) {
+		if state == http.StateNew {
+			connections++
+		}
+	}
+	server.Start()
+	defer server.Close()
+
+	client := &BitbucketClient{Server: server.URL, Token: "token", HTTPClient: server.Client()}
+
+	for i := 0; i < 5; i++ {
+		// the response body is deliberately neither read nor closed
+		if _, err := client.Get("/rest/api/1.0/admin/groups"); err != nil {
+			t.Fatalf("err: %s", err)
+		}
+	}
+
+	if connections != 1 {
+		t.Fatalf("expected a single connection to be reused, got %d connections", connections)
+	}
+}
+
+func TestBitbucketClient_Limiter(t *testing.T) {
+	core := transport.NewLimiter(10, 0)
+	workzone := transport.NewLimiter(2, 0)
+	upm := transport.NewLimiter(1, 0)
+
+	client := &BitbucketClient{Limiters: RequestLimiters{Core: core, Workzone: workzone, UPM: upm}}
+
+	endpoints := map[string]*transport.Limiter{
+		"/rest/api/1.0/projects":                          core,
+		"/rest/workzoneresource/1.0/branch/automerge/P/r": workzone,
+		"/rest/plugins/1.0/?os_authType=basic":            upm,
+	}
+	for endpoint, expected := range endpoints {
+		if got := client.limiter(endpoint); got != expected {
+			t.Errorf("unexpected limiter for %s", endpoint)
+		}
+	}
+}
+
+func TestBitbucketClient_RedactsDebugLogs(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		fmt.Fprint(w, `{"errors": [{"message": "invalid"}], "token": "response-token"}`)
+	}))
+	defer server.Close()
+
+	var logs bytes.Buffer
+	log.SetOutput(&logs)
+	defer log.SetOutput(os.Stderr)
+
+	client := &BitbucketClient{Server: server.URL, Username: "admin", Password: "basic-secret", HTTPClient: server.Client()}
+
+	_, err := client.Post("/rest/api/1.0/admin/users?name=jdoe&password=query-secret", bytes.NewBufferString(`{"password": "payload-secret"}`))
+	if err == nil {
+		t.Fatal("expected an API error")
+	}
+
+	for _, secret := range []string{"basic-secret", "query-secret", "payload-secret", "response-token"} {
+		if strings.Contains(logs.String(), secret) || strings.Contains(err.Error(), secret) {
+			t.Errorf("secret %q leaked into logs or error", secret)
+		}
+	}
+}
+
+func TestBitbucketClient_RequestTimeout(t *testing.T) {
+	done := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-done
+	}))
+	defer server.Close()
+	defer close(done)
+
+	client := &BitbucketClient{Server: server.URL, Token: "token", HTTPClient: server.Client(), RequestTimeout: 50 * time.Millisecond}
+
+	_, err := client.Get("/rest/api/1.0/projects")
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected deadline exceeded, got %v", err)
+	}
+}
+
+func TestBitbucketClient_Cancelled(t *testing.T) {
+	done := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-done
+	}))
+	defer server.Close()
+	defer close(done)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	client := &BitbucketClient{Server: server.URL, Token: "token", HTTPClient: server.Client(), Context: ctx}
+
+	time.AfterFunc(50*time.Millisecond, cancel)
+
+	_, err := client.Get("/rest/api/1.0/projects")
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected cancellation, got %v", err)
+	}
+}
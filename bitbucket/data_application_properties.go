@@ -40,32 +40,37 @@ func dataSourceApplicationProperties() *schema.Resource {
 
 func dataSourceApplicationPropertiesRead(d *schema.ResourceData, m interface{}) error {
 	client := m.(*BitbucketServerProvider).BitbucketClient
-	req, err := client.Get("/rest/api/1.0/application-properties")
-
+	applicationProperties, err := readApplicationProperties(client)
 	if err != nil {
 		return err
 	}
 
-	if req.StatusCode == 200 {
+	d.SetId(applicationProperties.Version)
+	_ = d.Set("version", applicationProperties.Version)
+	_ = d.Set("build_number", applicationProperties.BuildNumber)
+	_ = d.Set("build_date", applicationProperties.BuildDate)
+	_ = d.Set("display_name", applicationProperties.DisplayName)
+
+	return nil
+}
 
-		var applicationProperties ApplicationProperties
+func readApplicationProperties(client *BitbucketClient) (*ApplicationProperties, error) {
+	req, err := client.Get("/rest/api/1.0/application-properties")
+	if err != nil {
+		return nil, err
+	}
 
-		body, readerr := ioutil.ReadAll(req.Body)
-		if readerr != nil {
-			return readerr
-		}
+	var applicationProperties ApplicationProperties
 
-		decodeerr := json.Unmarshal(body, &applicationProperties)
-		if decodeerr != nil {
-			return decodeerr
-		}
+	body, readerr := ioutil.ReadAll(req.Body)
+	if readerr != nil {
+		return nil, readerr
+	}
 
-		d.SetId(applicationProperties.Version)
-		_ = d.Set("version", applicationProperties.Version)
-		_ = d.Set("build_number", applicationProperties.BuildNumber)
-		_ = d.Set("build_date", applicationProperties.BuildDate)
-		_ = d.Set("display_name", applicationProperties.DisplayName)
+	decodeerr := json.Unmarshal(body, &applicationProperties)
+	if decodeerr != nil {
+		return nil, decodeerr
 	}
 
-	return nil
+	return &applicationProperties, nil
 }
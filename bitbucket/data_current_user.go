@@ -0,0 +1,136 @@
+package bitbucket
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"strings"
+
+	"github.com/hashicorp/terraform/helper/schema"
+)
+
+// globalPermissions lists the global permissions from the highest to the lowest level.
+var globalPermissions = []string{"SYS_ADMIN", "ADMIN", "PROJECT_CREATE", "LICENSED_USER"}
+
+func dataSourceCurrentUser() *schema.Resource {
+	return &schema.Resource{
+		Read: dataSourceCurrentUserRead,
+
+		Schema: map[string]*schema.Schema{
+			"name": {
+				Type:     schema.TypeString,
+				Computed: true,
+			},
+			"slug": {
+				Type:     schema.TypeString,
+				Computed: true,
+			},
+			"user_id": {
+				Type:     schema.TypeInt,
+				Computed: true,
+			},
+			"display_name": {
+				Type:     schema.TypeString,
+				Computed: true,
+			},
+			"email_address": {
+				Type:     schema.TypeString,
+				Computed: true,
+			},
+			"global_permission": {
+				Type:     schema.TypeString,
+				Computed: true,
+			},
+		},
+	}
+}
+
+func dataSourceCurrentUserRead(d *schema.ResourceData, m interface{}) error {
+	client := m.(*BitbucketServerProvider).BitbucketClient
+
+	name, err := readAuthenticatedUserName(client)
+	if err != nil {
+		return err
+	}
+	if name == "" {
+		return fmt.Errorf("the provider is not authenticated")
+	}
+
+	user, err := findUser(client, name, "")
+	if err != nil {
+		return err
+	}
+	if user == nil {
+		return fmt.Errorf("user %s not found", name)
+	}
+
+	permission := ""
+	for _, globalPermission := range globalPermissions {
+		match, err := findUser(client, name, globalPermission)
+		if err != nil {
+			return err
+		}
+		if match != nil {
+			permission = globalPermission
+			break
+		}
+	}
+
+	d.SetId(user.Slug)
+	_ = d.Set("name", user.Name)
+	_ = d.Set("slug", user.Slug)
+	_ = d.Set("user_id", user.UserId)
+	_ = d.Set("display_name", user.DisplayName)
+	_ = d.Set("email_address", user.EmailAddress)
+	_ = d.Set("global_permission", permission)
+
+	return nil
+}
+
+// readAuthenticatedUserName returns the name of the user the client authenticates as, or an
+// empty string if the request was served anonymously.
+func readAuthenticatedUserName(client *BitbucketClient) (string, error) {
+	// servlets outside /rest only evaluate basic auth credentials when asked to
+	endpoint := "/plugins/servlet/applinks/whoami"
+	if client.Token == "" {
+		endpoint += "?os_authType=basic"
+	}
+
+	resp, err := client.Get(endpoint)
+	if err != nil {
+		return "", err
+	}
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return "", err
+	}
+
+	return strings.TrimSpace(string(body)), nil
+}
+
+// findUser looks up a user by name, optionally limited to users holding at least the given
+// global permission. The user list is visible to every licensed user, unlike the admin
+// permission endpoints.
+func findUser(client *BitbucketClient, name string, permission string) (*User, error) {
+	params := url.Values{}
+	params.Set("filter", name)
+	if permission != "" {
+		params.Set("permission", permission)
+	}
+
+	var users []User
+	err := client.GetAllPages("/rest/api/1.0/users", params, &users)
+	if err != nil {
+		return nil, err
+	}
+
+	// API only filters but we need to find an exact match
+	for _, user := range users {
+		if user.Name == name {
+			return &user, nil
+		}
+	}
+
+	return nil, nil
+}
@@ -0,0 +1,29 @@
+package bitbucket
+
+import (
+	"testing"
+
+	"github.com/hashicorp/terraform/helper/resource"
+)
+
+func TestAccBitbucketDataCurrentUser(t *testing.T) {
+	config := `
+		data "bitbucketserver_current_user" "test" {}
+	`
+
+	resource.Test(t, resource.TestCase{
+		PreCheck:  func() { testAccPreCheck(t) },
+		Providers: testAccProviders,
+		Steps: []resource.TestStep{
+			{
+				Config: config,
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttr("data.bitbucketserver_current_user.test", "name", "admin"),
+					resource.TestCheckResourceAttr("data.bitbucketserver_current_user.test", "slug", "admin"),
+					resource.TestCheckResourceAttr("data.bitbucketserver_current_user.test", "user_id", "2"),
+					resource.TestCheckResourceAttr("data.bitbucketserver_current_user.test", "global_permission", "SYS_ADMIN"),
+				),
+			},
+		},
+	})
+}
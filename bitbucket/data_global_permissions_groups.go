@@ -1,8 +1,6 @@
 package bitbucket
 
 import (
-	"encoding/json"
-	"fmt"
 	"github.com/hashicorp/terraform/helper/schema"
 	"net/url"
 )
@@ -19,15 +17,6 @@ type GlobalPermissionsGroup struct {
 	Permission string
 }
 
-type PaginatedGlobalPermissionsGroups struct {
-	Values        []PaginatedGlobalPermissionsGroupsValue `json:"values,omitempty"`
-	Size          int                                     `json:"size,omitempty"`
-	Limit         int                                     `json:"limit,omitempty"`
-	IsLastPage    bool                                    `json:"isLastPage,omitempty"`
-	Start         int                                     `json:"start,omitempty"`
-	NextPageStart int                                     `json:"nextPageStart,omitempty"`
-}
-
 func dataSourceGlobalPermissionsGroups() *schema.Resource {
 	return &schema.Resource{
 		Read: dataSourceGlobalPermissionsGroupsRead,
@@ -80,48 +69,24 @@ func dataSourceGlobalPermissionsGroupsRead(d *schema.ResourceData, m interface{}
 func readGlobalPermissionsGroups(m interface{}, filter string) ([]GlobalPermissionsGroup, error) {
 	client := m.(*BitbucketServerProvider).BitbucketClient
 
-	resourceURL := "/rest/api/1.0/admin/permissions/groups"
-
+	params := url.Values{}
 	if filter != "" {
-		resourceURL += "?filter=" + url.QueryEscape(filter)
+		params.Set("filter", filter)
 	}
 
-	var groupGroups PaginatedGlobalPermissionsGroups
-	var groups []GlobalPermissionsGroup
-
-	for {
-		resp, err := client.Get(resourceURL)
-		if err != nil {
-			return nil, err
-		}
-
-		decoder := json.NewDecoder(resp.Body)
-		err = decoder.Decode(&groupGroups)
-		if err != nil {
-			return nil, err
-		}
-
-		for _, group := range groupGroups.Values {
-			g := GlobalPermissionsGroup{
-				Name:       group.Group.Name,
-				Permission: group.Permission,
-			}
-			groups = append(groups, g)
-		}
-
-		if groupGroups.IsLastPage == false {
-			resourceURL = fmt.Sprintf("/rest/api/1.0/admin/permissions/groups?start=%d",
-				groupGroups.NextPageStart,
-			)
-
-			if filter != "" {
-				resourceURL += "&filter=" + url.QueryEscape(filter)
-			}
+	var values []PaginatedGlobalPermissionsGroupsValue
+	err := client.GetAllPages("/rest/api/1.0/admin/permissions/groups", params, &values)
+	if err != nil {
+		return nil, err
+	}
 
-			groupGroups = PaginatedGlobalPermissionsGroups{}
-		} else {
-			break
+	var groups []GlobalPermissionsGroup
+	for _, group := range values {
+		g := GlobalPermissionsGroup{
+			Name:       group.Group.Name,
+			Permission: group.Permission,
 		}
+		groups = append(groups, g)
 	}
 
 	return groups, nil
@@ -1,8 +1,6 @@
 package bitbucket
 
 import (
-	"encoding/json"
-	"fmt"
 	"github.com/hashicorp/terraform/helper/schema"
 	"net/url"
 )
@@ -25,15 +23,6 @@ type GlobalPermissionsUser struct {
 	Permission   string
 }
 
-type PaginatedGlobalPermissionsUsers struct {
-	Values        []PaginatedGlobalPermissionsUsersValue `json:"values,omitempty"`
-	Size          int                                    `json:"size,omitempty"`
-	Limit         int                                    `json:"limit,omitempty"`
-	IsLastPage    bool                                   `json:"isLastPage,omitempty"`
-	Start         int                                    `json:"start,omitempty"`
-	NextPageStart int                                    `json:"nextPageStart,omitempty"`
-}
-
 func dataSourceGlobalPermissionsUsers() *schema.Resource {
 	return &schema.Resource{
 		Read: dataSourceGlobalPermissionsUsersRead,
@@ -101,51 +90,27 @@ func dataSourceGlobalPermissionsUsersRead(d *schema.ResourceData, m interface{})
 func readGlobalPermissionsUsers(m interface{}, filter string) ([]GlobalPermissionsUser, error) {
 	client := m.(*BitbucketServerProvider).BitbucketClient
 
-	resourceURL := "/rest/api/1.0/admin/permissions/users"
-
+	params := url.Values{}
 	if filter != "" {
-		resourceURL += "?filter=" + url.QueryEscape(filter)
+		params.Set("filter", filter)
 	}
 
-	var globalUsers PaginatedGlobalPermissionsUsers
-	var users []GlobalPermissionsUser
-
-	for {
-		resp, err := client.Get(resourceURL)
-		if err != nil {
-			return nil, err
-		}
-
-		decoder := json.NewDecoder(resp.Body)
-		err = decoder.Decode(&globalUsers)
-		if err != nil {
-			return nil, err
-		}
-
-		for _, user := range globalUsers.Values {
-			g := GlobalPermissionsUser{
-				Name:         user.User.Name,
-				EmailAddress: user.User.EmailAddress,
-				DisplayName:  user.User.DisplayName,
-				Active:       user.User.Active,
-				Permission:   user.Permission,
-			}
-			users = append(users, g)
-		}
-
-		if globalUsers.IsLastPage == false {
-			resourceURL = fmt.Sprintf("/rest/api/1.0/admin/permissions/users?start=%d",
-				globalUsers.NextPageStart,
-			)
-
-			if filter != "" {
-				resourceURL += "&filter=" + url.QueryEscape(filter)
-			}
+	var values []PaginatedGlobalPermissionsUsersValue
+	err := client.GetAllPages("/rest/api/1.0/admin/permissions/users", params, &values)
+	if err != nil {
+		return nil, err
+	}
 
-			globalUsers = PaginatedGlobalPermissionsUsers{}
-		} else {
-			break
+	var users []GlobalPermissionsUser
+	for _, user := range values {
+		g := GlobalPermissionsUser{
+			Name:         user.User.Name,
+			EmailAddress: user.User.EmailAddress,
+			DisplayName:  user.User.DisplayName,
+			Active:       user.User.Active,
+			Permission:   user.Permission,
 		}
+		users = append(users, g)
 	}
 
 	return users, nil
@@ -1,8 +1,6 @@
 package bitbucket
 
 import (
-	"encoding/json"
-	"fmt"
 	"net/url"
 
 	"github.com/hashicorp/terraform/helper/schema"
@@ -22,15 +20,6 @@ type GroupUser struct {
 	Active       bool
 }
 
-type PaginatedGroupUsers struct {
-	Values        []PaginatedGroupUsersValue `json:"values,omitempty"`
-	Size          int                        `json:"size,omitempty"`
-	Limit         int                        `json:"limit,omitempty"`
-	IsLastPage    bool                       `json:"isLastPage,omitempty"`
-	Start         int                        `json:"start,omitempty"`
-	NextPageStart int                        `json:"nextPageStart,omitempty"`
-}
-
 func dataSourceGroupUsers() *schema.Resource {
 	return &schema.Resource{
 		Read: dataSourceGroupUsersRead,
@@ -97,53 +86,28 @@ func dataSourceGroupUsersRead(d *schema.ResourceData, m interface{}) error {
 func readGroupUsers(m interface{}, group string, filter string) ([]GroupUser, error) {
 	client := m.(*BitbucketServerProvider).BitbucketClient
 
-	resourceURL := fmt.Sprintf("/rest/api/1.0/admin/groups/more-members?context=%s&limit=100",
-		url.QueryEscape(group),
-	)
-
+	params := url.Values{}
+	params.Set("context", group)
+	params.Set("limit", "100")
 	if filter != "" {
-		resourceURL += "&filter=" + url.QueryEscape(filter)
+		params.Set("filter", filter)
 	}
 
-	var groupUsers PaginatedGroupUsers
-	var users []GroupUser
-
-	for {
-		resp, err := client.Get(resourceURL)
-		if err != nil {
-			return nil, err
-		}
-
-		decoder := json.NewDecoder(resp.Body)
-		err = decoder.Decode(&groupUsers)
-		if err != nil {
-			return nil, err
-		}
-
-		for _, user := range groupUsers.Values {
-			g := GroupUser{
-				Name:         user.Name,
-				EmailAddress: user.EmailAddress,
-				DisplayName:  user.DisplayName,
-				Active:       user.Active,
-			}
-			users = append(users, g)
-		}
-
-		if groupUsers.IsLastPage == false {
-			resourceURL = fmt.Sprintf("/rest/api/1.0/admin/groups/more-members?context=%s&limit=100&start=%d",
-				group,
-				groupUsers.NextPageStart,
-			)
-
-			if filter != "" {
-				resourceURL += "&filter=" + url.QueryEscape(filter)
-			}
+	var values []PaginatedGroupUsersValue
+	err := client.GetAllPages("/rest/api/1.0/admin/groups/more-members", params, &values)
+	if err != nil {
+		return nil, err
+	}
 
-			groupUsers = PaginatedGroupUsers{}
-		} else {
-			break
+	var users []GroupUser
+	for _, user := range values {
+		g := GroupUser{
+			Name:         user.Name,
+			EmailAddress: user.EmailAddress,
+			DisplayName:  user.DisplayName,
+			Active:       user.Active,
 		}
+		users = append(users, g)
 	}
 
 	return users, nil
@@ -1,8 +1,6 @@
 package bitbucket
 
 import (
-	"encoding/json"
-	"fmt"
 	"github.com/hashicorp/terraform/helper/schema"
 	"net/url"
 )
@@ -11,15 +9,6 @@ type PaginatedGroupsValue struct {
 	Name string `json:"name,omitempty"`
 }
 
-type PaginatedGroups struct {
-	Values        []PaginatedGroupsValue `json:"values,omitempty"`
-	Size          int                    `json:"size,omitempty"`
-	Limit         int                    `json:"limit,omitempty"`
-	IsLastPage    bool                   `json:"isLastPage,omitempty"`
-	Start         int                    `json:"start,omitempty"`
-	NextPageStart int                    `json:"nextPageStart,omitempty"`
-}
-
 func dataSourceGroups() *schema.Resource {
 	return &schema.Resource{
 		Read: dataSourceGroupsRead,
@@ -67,43 +56,20 @@ func dataSourceGroupsRead(d *schema.ResourceData, m interface{}) error {
 func readGroups(m interface{}, filter string) ([]string, error) {
 	client := m.(*BitbucketServerProvider).BitbucketClient
 
-	resourceURL := "/rest/api/1.0/admin/groups"
+	params := url.Values{}
 	if filter != "" {
-		resourceURL += "?filter=" + url.QueryEscape(filter)
+		params.Set("filter", filter)
 	}
 
-	var paginatedGroups PaginatedGroups
-	var groups []string
-
-	for {
-		resp, err := client.Get(resourceURL)
-		if err != nil {
-			return nil, err
-		}
-
-		decoder := json.NewDecoder(resp.Body)
-		err = decoder.Decode(&paginatedGroups)
-		if err != nil {
-			return nil, err
-		}
-
-		for _, group := range paginatedGroups.Values {
-			groups = append(groups, group.Name)
-		}
-
-		if paginatedGroups.IsLastPage == false {
-			resourceURL = fmt.Sprintf("/rest/api/1.0/admin/groups?start=%d",
-				paginatedGroups.NextPageStart,
-			)
-
-			if filter != "" {
-				resourceURL += "&filter=" + url.QueryEscape(filter)
-			}
+	var values []PaginatedGroupsValue
+	err := client.GetAllPages("/rest/api/1.0/admin/groups", params, &values)
+	if err != nil {
+		return nil, err
+	}
 
-			paginatedGroups = PaginatedGroups{}
-		} else {
-			break
-		}
+	var groups []string
+	for _, group := range values {
+		groups = append(groups, group.Name)
 	}
 
 	return groups, nil
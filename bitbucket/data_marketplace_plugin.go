@@ -0,0 +1,107 @@
+package bitbucket
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"strconv"
+
+	"github.com/hashicorp/terraform/helper/schema"
+)
+
+func dataSourceMarketplacePlugin() *schema.Resource {
+	return &schema.Resource{
+		Read: dataSourceMarketplacePluginRead,
+
+		Schema: map[string]*schema.Schema{
+			"key": {
+				Type:     schema.TypeString,
+				Required: true,
+			},
+			"compatible_with_server": {
+				Type:          schema.TypeBool,
+				Optional:      true,
+				Default:       false,
+				ConflictsWith: []string{"bitbucket_build"},
+			},
+			"bitbucket_build": {
+				Type:          schema.TypeInt,
+				Optional:      true,
+				ConflictsWith: []string{"compatible_with_server"},
+			},
+			"version": {
+				Type:     schema.TypeString,
+				Computed: true,
+			},
+			"release_date": {
+				Type:     schema.TypeString,
+				Computed: true,
+			},
+			"download_url": {
+				Type:     schema.TypeString,
+				Computed: true,
+			},
+			"data_center_approved": {
+				Type:     schema.TypeBool,
+				Computed: true,
+			},
+		},
+	}
+}
+
+func dataSourceMarketplacePluginRead(d *schema.ResourceData, m interface{}) error {
+	provider := m.(*BitbucketServerProvider)
+	key := d.Get("key").(string)
+
+	build := d.Get("bitbucket_build").(int)
+	if d.Get("compatible_with_server").(bool) {
+		serverInfo, err := provider.serverInfo()
+		if err != nil {
+			return err
+		}
+		build = serverInfo.BuildNumber
+	}
+
+	marketplaceVersion, err := readLatestMarketplacePluginVersion(key, build, provider)
+	if err != nil {
+		return err
+	}
+
+	d.SetId(fmt.Sprintf("%s/%s", key, marketplaceVersion.Version))
+	_ = d.Set("version", marketplaceVersion.Version)
+	_ = d.Set("release_date", marketplaceVersion.Release.Date)
+	_ = d.Set("download_url", provider.MarketplaceClient.ResolveURL(marketplaceVersion.Embedded.Artifact.Links.Binary.Href))
+	_ = d.Set("data_center_approved", marketplaceVersion.DataCenterApproved())
+
+	return nil
+}
+
+// readLatestMarketplacePluginVersion returns the latest version of the plugin, limited to versions
+// compatible with the given Bitbucket build unless the build is 0.
+func readLatestMarketplacePluginVersion(key string, build int, provider *BitbucketServerProvider) (*PluginMarketplaceVersion, error) {
+	params := url.Values{}
+	params.Set("application", "bitbucket")
+	if build > 0 {
+		params.Set("applicationBuild", strconv.Itoa(build))
+	}
+
+	marketplaceRequest, err := provider.MarketplaceClient.Get(fmt.Sprintf("/rest/2/addons/%s/versions/latest?%s", key, params.Encode()))
+	if err != nil {
+		return nil, err
+	}
+
+	var marketplaceVersion PluginMarketplaceVersion
+
+	body, readerr := ioutil.ReadAll(marketplaceRequest.Body)
+	if readerr != nil {
+		return nil, readerr
+	}
+
+	decodeerr := json.Unmarshal(body, &marketplaceVersion)
+	if decodeerr != nil {
+		return nil, decodeerr
+	}
+
+	return &marketplaceVersion, nil
+}
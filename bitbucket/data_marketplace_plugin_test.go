@@ -0,0 +1,32 @@
+package bitbucket
+
+import (
+	"testing"
+
+	"github.com/hashicorp/terraform/helper/resource"
+)
+
+func TestAccBitbucketDataMarketplacePlugin_compatibleWithServer(t *testing.T) {
+	config := `
+		data "bitbucketserver_marketplace_plugin" "test" {
+			key                    = "com.plugin.commitgraph.commitgraph"
+			compatible_with_server = true
+		}
+	`
+
+	resource.Test(t, resource.TestCase{
+		PreCheck:  func() { testAccPreCheck(t) },
+		Providers: testAccProviders,
+		Steps: []resource.TestStep{
+			{
+				Config: config,
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttrSet("data.bitbucketserver_marketplace_plugin.test", "version"),
+					resource.TestCheckResourceAttrSet("data.bitbucketserver_marketplace_plugin.test", "release_date"),
+					resource.TestCheckResourceAttrSet("data.bitbucketserver_marketplace_plugin.test", "download_url"),
+					resource.TestCheckResourceAttrSet("data.bitbucketserver_marketplace_plugin.test", "data_center_approved"),
+				),
+			},
+		},
+	})
+}
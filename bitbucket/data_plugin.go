@@ -1,6 +1,7 @@
 package bitbucket
 
 import (
+	"fmt"
 	"github.com/hashicorp/terraform/helper/schema"
 )
 
@@ -156,6 +157,15 @@ func dataSourcePlugin() *schema.Resource {
 }
 
 func dataSourcePluginRead(d *schema.ResourceData, m interface{}) error {
-	d.SetId(d.Get("key").(string))
-	return resourcePluginRead(d, m)
+	key := d.Get("key").(string)
+	d.SetId(key)
+	if err := resourcePluginRead(d, m); err != nil {
+		return err
+	}
+
+	// the resource read clears the ID of missing plugins, which is an error for a data source
+	if d.Id() == "" {
+		return fmt.Errorf("plugin %s not found", key)
+	}
+	return nil
 }
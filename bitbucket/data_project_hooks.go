@@ -1,7 +1,6 @@
 package bitbucket
 
 import (
-	"encoding/json"
 	"fmt"
 	"github.com/hashicorp/terraform/helper/schema"
 	"github.com/hashicorp/terraform/helper/validation"
@@ -39,15 +38,6 @@ type ProjectHook struct {
 	ScopeResourceId int
 }
 
-type PaginatedProjectHooks struct {
-	Values        []PaginatedProjectHooksValue `json:"values,omitempty"`
-	Size          int                          `json:"size,omitempty"`
-	Limit         int                          `json:"limit,omitempty"`
-	IsLastPage    bool                         `json:"isLastPage,omitempty"`
-	Start         int                          `json:"start,omitempty"`
-	NextPageStart int                          `json:"nextPageStart,omitempty"`
-}
-
 func dataSourceProjectHooks() *schema.Resource {
 	return &schema.Resource{
 		Read: dataSourceProjectHooksRead,
@@ -150,56 +140,33 @@ func readProjectHooks(m interface{}, project string, typeFilter string) ([]Proje
 		project,
 	)
 
+	params := url.Values{}
 	if typeFilter != "" {
-		resourceURL += "?type=" + url.QueryEscape(typeFilter)
+		params.Set("type", typeFilter)
 	}
 
-	var projectHooks PaginatedProjectHooks
-	var hooks []ProjectHook
-
-	for {
-		resp, err := client.Get(resourceURL)
-		if err != nil {
-			return nil, err
-		}
-
-		decoder := json.NewDecoder(resp.Body)
-		err = decoder.Decode(&projectHooks)
-		if err != nil {
-			return nil, err
-		}
-
-		for _, hook := range projectHooks.Values {
-			sort.Strings(hook.Details.ScopeTypes)
-			h := ProjectHook{
-				Key:             hook.Details.Key,
-				Name:            hook.Details.Name,
-				Type:            hook.Details.Type,
-				Description:     hook.Details.Description,
-				Version:         hook.Details.Version,
-				ScopeTypes:      hook.Details.ScopeTypes,
-				Enabled:         hook.Enabled,
-				Configured:      hook.Configured,
-				ScopeType:       hook.Scope.Type,
-				ScopeResourceId: hook.Scope.ResourceId,
-			}
-			hooks = append(hooks, h)
-		}
-
-		if projectHooks.IsLastPage == false {
-			resourceURL = fmt.Sprintf("/rest/api/1.0/projects/%s/settings/hooks?start=%d",
-				project,
-				projectHooks.NextPageStart,
-			)
-
-			if typeFilter != "" {
-				resourceURL += "&type=" + url.QueryEscape(typeFilter)
-			}
+	var values []PaginatedProjectHooksValue
+	err := client.GetAllPages(resourceURL, params, &values)
+	if err != nil {
+		return nil, err
+	}
 
-			projectHooks = PaginatedProjectHooks{}
-		} else {
-			break
+	var hooks []ProjectHook
+	for _, hook := range values {
+		sort.Strings(hook.Details.ScopeTypes)
+		h := ProjectHook{
+			Key:             hook.Details.Key,
+			Name:            hook.Details.Name,
+			Type:            hook.Details.Type,
+			Description:     hook.Details.Description,
+			Version:         hook.Details.Version,
+			ScopeTypes:      hook.Details.ScopeTypes,
+			Enabled:         hook.Enabled,
+			Configured:      hook.Configured,
+			ScopeType:       hook.Scope.Type,
+			ScopeResourceId: hook.Scope.ResourceId,
 		}
+		hooks = append(hooks, h)
 	}
 
 	return hooks, nil
@@ -1,7 +1,6 @@
 package bitbucket
 
 import (
-	"encoding/json"
 	"fmt"
 	"github.com/hashicorp/terraform/helper/schema"
 	"net/url"
@@ -19,15 +18,6 @@ type ProjectPermissionsGroup struct {
 	Permission string
 }
 
-type PaginatedProjectPermissionsGroups struct {
-	Values        []PaginatedProjectPermissionsGroupsValue `json:"values,omitempty"`
-	Size          int                                      `json:"size,omitempty"`
-	Limit         int                                      `json:"limit,omitempty"`
-	IsLastPage    bool                                     `json:"isLastPage,omitempty"`
-	Start         int                                      `json:"start,omitempty"`
-	NextPageStart int                                      `json:"nextPageStart,omitempty"`
-}
-
 func dataSourceProjectPermissionsGroups() *schema.Resource {
 	return &schema.Resource{
 		Read: dataSourceProjectPermissionsGroupsRead,
@@ -88,48 +78,41 @@ func readProjectPermissionsGroups(m interface{}, project string, filter string)
 		project,
 	)
 
+	params := url.Values{}
 	if filter != "" {
-		resourceURL += "?filter=" + url.QueryEscape(filter)
+		params.Set("filter", filter)
 	}
 
-	var projectGroups PaginatedProjectPermissionsGroups
-	var groups []ProjectPermissionsGroup
-
-	for {
-		resp, err := client.Get(resourceURL)
-		if err != nil {
-			return nil, err
-		}
-
-		decoder := json.NewDecoder(resp.Body)
-		err = decoder.Decode(&projectGroups)
-		if err != nil {
-			return nil, err
-		}
+	var values []PaginatedProjectPermissionsGroupsValue
+	err := client.GetAllPages(resourceURL, params, &values)
+	if err != nil {
+		return nil, err
+	}
 
-		for _, group := range projectGroups.Values {
-			g := ProjectPermissionsGroup{
-				Name:       group.Group.Name,
-				Permission: group.Permission,
-			}
-			groups = append(groups, g)
+	var groups []ProjectPermissionsGroup
+	for _, group := range values {
+		g := ProjectPermissionsGroup{
+			Name:       group.Group.Name,
+			Permission: group.Permission,
 		}
+		groups = append(groups, g)
+	}
 
-		if projectGroups.IsLastPage == false {
-			resourceURL = fmt.Sprintf("/rest/api/1.0/projects/%s/permissions/groups?start=%d",
-				project,
-				projectGroups.NextPageStart,
-			)
+	return groups, nil
+}
 
-			if filter != "" {
-				resourceURL += "&filter=" + url.QueryEscape(filter)
-			}
+func projectPermissionsGroupsScope(project string) string {
+	return fmt.Sprintf("projects/%s/permissions/groups", project)
+}
 
-			projectGroups = PaginatedProjectPermissionsGroups{}
-		} else {
-			break
-		}
+// readCachedProjectPermissionsGroups returns every group permission of the project, fetched once per run.
+func readCachedProjectPermissionsGroups(m interface{}, project string) ([]ProjectPermissionsGroup, error) {
+	value, err := m.(*BitbucketServerProvider).readCache.get(projectPermissionsGroupsScope(project), func() (interface{}, error) {
+		return readProjectPermissionsGroups(m, project, "")
+	})
+	if err != nil {
+		return nil, err
 	}
 
-	return groups, nil
+	return value.([]ProjectPermissionsGroup), nil
 }
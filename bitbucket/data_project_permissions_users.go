@@ -1,7 +1,6 @@
 package bitbucket
 
 import (
-	"encoding/json"
 	"fmt"
 	"github.com/hashicorp/terraform/helper/schema"
 	"net/url"
@@ -25,15 +24,6 @@ type ProjectPermissionsUser struct {
 	Permission   string
 }
 
-type PaginatedProjectPermissionsUsers struct {
-	Values        []PaginatedProjectPermissionsUsersValue `json:"values,omitempty"`
-	Size          int                                     `json:"size,omitempty"`
-	Limit         int                                     `json:"limit,omitempty"`
-	IsLastPage    bool                                    `json:"isLastPage,omitempty"`
-	Start         int                                     `json:"start,omitempty"`
-	NextPageStart int                                     `json:"nextPageStart,omitempty"`
-}
-
 func dataSourceProjectPermissionsUsers() *schema.Resource {
 	return &schema.Resource{
 		Read: dataSourceProjectPermissionsUsersRead,
@@ -109,51 +99,44 @@ func readProjectPermissionsUsers(m interface{}, project string, filter string) (
 		project,
 	)
 
+	params := url.Values{}
 	if filter != "" {
-		resourceURL += "?filter=" + url.QueryEscape(filter)
+		params.Set("filter", filter)
 	}
 
-	var projectUsers PaginatedProjectPermissionsUsers
-	var users []ProjectPermissionsUser
-
-	for {
-		resp, err := client.Get(resourceURL)
-		if err != nil {
-			return nil, err
-		}
-
-		decoder := json.NewDecoder(resp.Body)
-		err = decoder.Decode(&projectUsers)
-		if err != nil {
-			return nil, err
-		}
+	var values []PaginatedProjectPermissionsUsersValue
+	err := client.GetAllPages(resourceURL, params, &values)
+	if err != nil {
+		return nil, err
+	}
 
-		for _, user := range projectUsers.Values {
-			g := ProjectPermissionsUser{
-				Name:         user.User.Name,
-				EmailAddress: user.User.EmailAddress,
-				DisplayName:  user.User.DisplayName,
-				Active:       user.User.Active,
-				Permission:   user.Permission,
-			}
-			users = append(users, g)
+	var users []ProjectPermissionsUser
+	for _, user := range values {
+		g := ProjectPermissionsUser{
+			Name:         user.User.Name,
+			EmailAddress: user.User.EmailAddress,
+			DisplayName:  user.User.DisplayName,
+			Active:       user.User.Active,
+			Permission:   user.Permission,
 		}
+		users = append(users, g)
+	}
 
-		if projectUsers.IsLastPage == false {
-			resourceURL = fmt.Sprintf("/rest/api/1.0/projects/%s/permissions/users?start=%d",
-				project,
-				projectUsers.NextPageStart,
-			)
+	return users, nil
+}
 
-			if filter != "" {
-				resourceURL += "&filter=" + url.QueryEscape(filter)
-			}
+func projectPermissionsUsersScope(project string) string {
+	return fmt.Sprintf("projects/%s/permissions/users", project)
+}
 
-			projectUsers = PaginatedProjectPermissionsUsers{}
-		} else {
-			break
-		}
+// readCachedProjectPermissionsUsers returns every user permission of the project, fetched once per run.
+func readCachedProjectPermissionsUsers(m interface{}, project string) ([]ProjectPermissionsUser, error) {
+	value, err := m.(*BitbucketServerProvider).readCache.get(projectPermissionsUsersScope(project), func() (interface{}, error) {
+		return readProjectPermissionsUsers(m, project, "")
+	})
+	if err != nil {
+		return nil, err
 	}
 
-	return users, nil
+	return value.([]ProjectPermissionsUser), nil
 }
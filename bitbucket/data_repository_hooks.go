@@ -1,7 +1,6 @@
 package bitbucket
 
 import (
-	"encoding/json"
 	"fmt"
 	"github.com/hashicorp/terraform/helper/schema"
 	"github.com/hashicorp/terraform/helper/validation"
@@ -39,15 +38,6 @@ type RepositoryHook struct {
 	ScopeResourceId int
 }
 
-type PaginatedRepositoryHooks struct {
-	Values        []PaginatedRepositoryHooksValue `json:"values,omitempty"`
-	Size          int                             `json:"size,omitempty"`
-	Limit         int                             `json:"limit,omitempty"`
-	IsLastPage    bool                            `json:"isLastPage,omitempty"`
-	Start         int                             `json:"start,omitempty"`
-	NextPageStart int                             `json:"nextPageStart,omitempty"`
-}
-
 func dataSourceRepositoryHooks() *schema.Resource {
 	return &schema.Resource{
 		Read: dataSourceRepositoryHooksRead,
@@ -155,57 +145,33 @@ func readRepositoryHooks(m interface{}, project string, repository string, typeF
 		repository,
 	)
 
+	params := url.Values{}
 	if typeFilter != "" {
-		resourceURL += "?type=" + url.QueryEscape(typeFilter)
+		params.Set("type", typeFilter)
 	}
 
-	var repositoryHooks PaginatedRepositoryHooks
-	var hooks []RepositoryHook
-
-	for {
-		resp, err := client.Get(resourceURL)
-		if err != nil {
-			return nil, err
-		}
-
-		decoder := json.NewDecoder(resp.Body)
-		err = decoder.Decode(&repositoryHooks)
-		if err != nil {
-			return nil, err
-		}
-
-		for _, hook := range repositoryHooks.Values {
-			sort.Strings(hook.Details.ScopeTypes)
-			h := RepositoryHook{
-				Key:             hook.Details.Key,
-				Name:            hook.Details.Name,
-				Type:            hook.Details.Type,
-				Description:     hook.Details.Description,
-				Version:         hook.Details.Version,
-				ScopeTypes:      hook.Details.ScopeTypes,
-				Enabled:         hook.Enabled,
-				Configured:      hook.Configured,
-				ScopeType:       hook.Scope.Type,
-				ScopeResourceId: hook.Scope.ResourceId,
-			}
-			hooks = append(hooks, h)
-		}
-
-		if repositoryHooks.IsLastPage == false {
-			resourceURL = fmt.Sprintf("/rest/api/1.0/projects/%s/repos/%s/settings/hooks?start=%d",
-				project,
-				repository,
-				repositoryHooks.NextPageStart,
-			)
-
-			if typeFilter != "" {
-				resourceURL += "&type=" + url.QueryEscape(typeFilter)
-			}
+	var values []PaginatedRepositoryHooksValue
+	err := client.GetAllPages(resourceURL, params, &values)
+	if err != nil {
+		return nil, err
+	}
 
-			repositoryHooks = PaginatedRepositoryHooks{}
-		} else {
-			break
+	var hooks []RepositoryHook
+	for _, hook := range values {
+		sort.Strings(hook.Details.ScopeTypes)
+		h := RepositoryHook{
+			Key:             hook.Details.Key,
+			Name:            hook.Details.Name,
+			Type:            hook.Details.Type,
+			Description:     hook.Details.Description,
+			Version:         hook.Details.Version,
+			ScopeTypes:      hook.Details.ScopeTypes,
+			Enabled:         hook.Enabled,
+			Configured:      hook.Configured,
+			ScopeType:       hook.Scope.Type,
+			ScopeResourceId: hook.Scope.ResourceId,
 		}
+		hooks = append(hooks, h)
 	}
 
 	return hooks, nil
@@ -1,7 +1,6 @@
 package bitbucket
 
 import (
-	"encoding/json"
 	"fmt"
 	"github.com/hashicorp/terraform/helper/schema"
 	"net/url"
@@ -19,15 +18,6 @@ type RepositoryPermissionsGroup struct {
 	Permission string
 }
 
-type PaginatedRepositoryPermissionsGroups struct {
-	Values        []PaginatedRepositoryPermissionsGroupsValue `json:"values,omitempty"`
-	Size          int                                         `json:"size,omitempty"`
-	Limit         int                                         `json:"limit,omitempty"`
-	IsLastPage    bool                                        `json:"isLastPage,omitempty"`
-	Start         int                                         `json:"start,omitempty"`
-	NextPageStart int                                         `json:"nextPageStart,omitempty"`
-}
-
 func dataSourceRepositoryPermissionsGroups() *schema.Resource {
 	return &schema.Resource{
 		Read: dataSourceRepositoryPermissionsGroupsRead,
@@ -93,49 +83,41 @@ func readRepositoryPermissionsGroups(m interface{}, project string, repository s
 		url.QueryEscape(repository),
 	)
 
+	params := url.Values{}
 	if filter != "" {
-		resourceURL += "?filter=" + url.QueryEscape(filter)
+		params.Set("filter", filter)
 	}
 
-	var projectGroups PaginatedRepositoryPermissionsGroups
-	var groups []RepositoryPermissionsGroup
-
-	for {
-		resp, err := client.Get(resourceURL)
-		if err != nil {
-			return nil, err
-		}
-
-		decoder := json.NewDecoder(resp.Body)
-		err = decoder.Decode(&projectGroups)
-		if err != nil {
-			return nil, err
-		}
+	var values []PaginatedRepositoryPermissionsGroupsValue
+	err := client.GetAllPages(resourceURL, params, &values)
+	if err != nil {
+		return nil, err
+	}
 
-		for _, group := range projectGroups.Values {
-			g := RepositoryPermissionsGroup{
-				Name:       group.Group.Name,
-				Permission: group.Permission,
-			}
-			groups = append(groups, g)
+	var groups []RepositoryPermissionsGroup
+	for _, group := range values {
+		g := RepositoryPermissionsGroup{
+			Name:       group.Group.Name,
+			Permission: group.Permission,
 		}
+		groups = append(groups, g)
+	}
 
-		if projectGroups.IsLastPage == false {
-			resourceURL = fmt.Sprintf("/rest/api/1.0/projects/%s/repos/%s/permissions/groups?start=%d",
-				url.QueryEscape(project),
-				url.QueryEscape(repository),
-				projectGroups.NextPageStart,
-			)
+	return groups, nil
+}
 
-			if filter != "" {
-				resourceURL += "&filter=" + url.QueryEscape(filter)
-			}
+func repositoryPermissionsGroupsScope(project string, repository string) string {
+	return fmt.Sprintf("projects/%s/repos/%s/permissions/groups", project, repository)
+}
 
-			projectGroups = PaginatedRepositoryPermissionsGroups{}
-		} else {
-			break
-		}
+// readCachedRepositoryPermissionsGroups returns every group permission of the repository, fetched once per run.
+func readCachedRepositoryPermissionsGroups(m interface{}, project string, repository string) ([]RepositoryPermissionsGroup, error) {
+	value, err := m.(*BitbucketServerProvider).readCache.get(repositoryPermissionsGroupsScope(project, repository), func() (interface{}, error) {
+		return readRepositoryPermissionsGroups(m, project, repository, "")
+	})
+	if err != nil {
+		return nil, err
 	}
 
-	return groups, nil
+	return value.([]RepositoryPermissionsGroup), nil
 }
@@ -1,7 +1,6 @@
 package bitbucket
 
 import (
-	"encoding/json"
 	"fmt"
 	"github.com/hashicorp/terraform/helper/schema"
 	"net/url"
@@ -25,15 +24,6 @@ type RepositoryPermissionsUser struct {
 	Permission   string
 }
 
-type PaginatedRepositoryPermissionsUsers struct {
-	Values        []PaginatedRepositoryPermissionsUsersValue `json:"values,omitempty"`
-	Size          int                                        `json:"size,omitempty"`
-	Limit         int                                        `json:"limit,omitempty"`
-	IsLastPage    bool                                       `json:"isLastPage,omitempty"`
-	Start         int                                        `json:"start,omitempty"`
-	NextPageStart int                                        `json:"nextPageStart,omitempty"`
-}
-
 func dataSourceRepositoryPermissionsUsers() *schema.Resource {
 	return &schema.Resource{
 		Read: dataSourceRepositoryPermissionsUsersRead,
@@ -114,52 +104,44 @@ func readRepositoryPermissionsUsers(m interface{}, project string, repository st
 		url.QueryEscape(repository),
 	)
 
+	params := url.Values{}
 	if filter != "" {
-		resourceURL += "?filter=" + url.QueryEscape(filter)
+		params.Set("filter", filter)
 	}
 
-	var projectUsers PaginatedRepositoryPermissionsUsers
-	var users []RepositoryPermissionsUser
-
-	for {
-		resp, err := client.Get(resourceURL)
-		if err != nil {
-			return nil, err
-		}
-
-		decoder := json.NewDecoder(resp.Body)
-		err = decoder.Decode(&projectUsers)
-		if err != nil {
-			return nil, err
-		}
+	var values []PaginatedRepositoryPermissionsUsersValue
+	err := client.GetAllPages(resourceURL, params, &values)
+	if err != nil {
+		return nil, err
+	}
 
-		for _, user := range projectUsers.Values {
-			g := RepositoryPermissionsUser{
-				Name:         user.User.Name,
-				EmailAddress: user.User.EmailAddress,
-				DisplayName:  user.User.DisplayName,
-				Active:       user.User.Active,
-				Permission:   user.Permission,
-			}
-			users = append(users, g)
+	var users []RepositoryPermissionsUser
+	for _, user := range values {
+		g := RepositoryPermissionsUser{
+			Name:         user.User.Name,
+			EmailAddress: user.User.EmailAddress,
+			DisplayName:  user.User.DisplayName,
+			Active:       user.User.Active,
+			Permission:   user.Permission,
 		}
+		users = append(users, g)
+	}
 
-		if projectUsers.IsLastPage == false {
-			resourceURL = fmt.Sprintf("/rest/api/1.0/projects/%s/repos/%s/permissions/users?start=%d",
-				url.QueryEscape(project),
-				url.QueryEscape(repository),
-				projectUsers.NextPageStart,
-			)
+	return users, nil
+}
 
-			if filter != "" {
-				resourceURL += "&filter=" + url.QueryEscape(filter)
-			}
+func repositoryPermissionsUsersScope(project string, repository string) string {
+	return fmt.Sprintf("projects/%s/repos/%s/permissions/users", project, repository)
+}
 
-			projectUsers = PaginatedRepositoryPermissionsUsers{}
-		} else {
-			break
-		}
+// readCachedRepositoryPermissionsUsers returns every user permission of the repository, fetched once per run.
+func readCachedRepositoryPermissionsUsers(m interface{}, project string, repository string) ([]RepositoryPermissionsUser, error) {
+	value, err := m.(*BitbucketServerProvider).readCache.get(repositoryPermissionsUsersScope(project, repository), func() (interface{}, error) {
+		return readRepositoryPermissionsUsers(m, project, repository, "")
+	})
+	if err != nil {
+		return nil, err
 	}
 
-	return users, nil
+	return value.([]RepositoryPermissionsUser), nil
 }
@@ -1,6 +1,7 @@
 package bitbucket
 
 import (
+	"fmt"
 	"github.com/hashicorp/terraform/helper/schema"
 )
 
@@ -30,6 +31,15 @@ func dataSourceUser() *schema.Resource {
 }
 
 func dataSourceUserRead(d *schema.ResourceData, m interface{}) error {
-	d.SetId(d.Get("name").(string))
-	return resourceUserRead(d, m)
+	name := d.Get("name").(string)
+	d.SetId(name)
+	if err := resourceUserRead(d, m); err != nil {
+		return err
+	}
+
+	// the resource read clears the ID of missing users, which is an error for a data source
+	if d.Id() == "" {
+		return fmt.Errorf("user %s not found", name)
+	}
+	return nil
 }
@@ -0,0 +1,63 @@
+package bitbucket
+
+import (
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/hashicorp/terraform/helper/schema"
+)
+
+// validateProjectPattern checks the syntax of a protected_projects pattern.
+func validateProjectPattern(v interface{}, k string) ([]string, []error) {
+	if _, err := path.Match(v.(string), ""); err != nil {
+		return nil, []error{fmt.Errorf("%s: invalid pattern %q: %s", k, v, err)}
+	}
+	return nil, nil
+}
+
+// protectedProject returns the protected_projects pattern matching the project key. Keys are
+// matched case-insensitively, as Bitbucket does.
+func (p *BitbucketServerProvider) protectedProject(key string) (string, bool) {
+	for _, pattern := range p.ProtectedProjects {
+		if matched, _ := path.Match(strings.ToUpper(pattern), strings.ToUpper(key)); matched {
+			return pattern, true
+		}
+	}
+	return "", false
+}
+
+// checkDeletionProtection returns an error if the object may not be deleted, either because its
+// deletion_protection flag is set or because its project is listed in protected_projects.
+func checkDeletionProtection(m interface{}, object string, project string, protected bool) error {
+	if protected {
+		return fmt.Errorf("%s has deletion_protection enabled, set it to false and apply before destroying or replacing it", object)
+	}
+
+	if pattern, ok := m.(*BitbucketServerProvider).protectedProject(project); ok {
+		return fmt.Errorf("%s is protected by the protected_projects pattern %q of the provider, remove the pattern before destroying or replacing it", object, pattern)
+	}
+
+	return nil
+}
+
+// checkReplacementProtection fails the plan if it replaces a protected object. The flag and the
+// project are taken from the prior state, so disabling the protection takes a separate apply.
+func checkReplacementProtection(d *schema.ResourceDiff, m interface{}, resourceSchema map[string]*schema.Schema, object string, project string) error {
+	if d.Id() == "" || !requiresReplacement(d, resourceSchema) {
+		return nil
+	}
+
+	protected, _ := d.GetChange("deletion_protection")
+	return checkDeletionProtection(m, object, project, protected.(bool))
+}
+
+// requiresReplacement reports whether the diff changes an attribute that forces a new resource.
+func requiresReplacement(d *schema.ResourceDiff, resourceSchema map[string]*schema.Schema) bool {
+	for key, attribute := range resourceSchema {
+		if attribute.ForceNew && d.HasChange(key) {
+			return true
+		}
+	}
+	return false
+}
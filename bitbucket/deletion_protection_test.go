@@ -0,0 +1,102 @@
+package bitbucket
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/terraform/config"
+	"github.com/hashicorp/terraform/helper/schema"
+	"github.com/hashicorp/terraform/terraform"
+)
+
+func TestProtectedProject(t *testing.T) {
+	provider := &BitbucketServerProvider{ProtectedProjects: []string{"PROD*", "CORE"}}
+
+	cases := map[string]bool{
+		"PROD":     true,
+		"PRODAPPS": true,
+		"prodapps": true,
+		"CORE":     true,
+		"CORE2":    false,
+		"TEST":     false,
+	}
+
+	for key, expected := range cases {
+		if _, protected := provider.protectedProject(key); protected != expected {
+			t.Errorf("expected %s protected to be %t", key, expected)
+		}
+	}
+}
+
+func TestValidateProjectPattern(t *testing.T) {
+	if _, errs := validateProjectPattern("PROD*", "protected_projects"); len(errs) != 0 {
+		t.Fatalf("unexpected errors %v", errs)
+	}
+	if _, errs := validateProjectPattern("PROD[", "protected_projects"); len(errs) != 1 {
+		t.Fatalf("expected an error for a malformed pattern, got %v", errs)
+	}
+}
+
+func TestResourceRepositoryDelete_DeletionProtection(t *testing.T) {
+	d := schema.TestResourceDataRaw(t, resourceRepository().Schema, map[string]interface{}{
+		"project":             "TEST",
+		"name":                "repo",
+		"deletion_protection": true,
+	})
+	d.SetId("TEST/repo")
+
+	err := resourceRepositoryDelete(d, &BitbucketServerProvider{})
+	if err == nil || !strings.Contains(err.Error(), "deletion_protection") {
+		t.Fatalf("expected a deletion_protection error, got %v", err)
+	}
+}
+
+func TestResourceProjectDelete_ProtectedProjects(t *testing.T) {
+	d := schema.TestResourceDataRaw(t, resourceProject().Schema, map[string]interface{}{
+		"key":  "PRODAPPS",
+		"name": "Production Apps",
+	})
+	d.SetId("PRODAPPS")
+
+	err := resourceProjectDelete(d, &BitbucketServerProvider{ProtectedProjects: []string{"PROD*"}})
+	if err == nil || !strings.Contains(err.Error(), `"PROD*"`) {
+		t.Fatalf("expected a protected_projects error, got %v", err)
+	}
+}
+
+func TestResourceProjectDiff_ReplacementProtection(t *testing.T) {
+	state := &terraform.InstanceState{
+		ID: "TEST",
+		Attributes: map[string]string{
+			"id":                  "TEST",
+			"key":                 "TEST",
+			"name":                "Test",
+			"public":              "false",
+			"deletion_protection": "true",
+		},
+	}
+
+	diff := func(raw map[string]interface{}) error {
+		rawConfig, err := config.NewRawConfig(raw)
+		if err != nil {
+			t.Fatalf("err: %s", err)
+		}
+		_, err = resourceProject().Diff(state, terraform.NewResourceConfig(rawConfig), &BitbucketServerProvider{})
+		return err
+	}
+
+	if err := diff(map[string]interface{}{"key": "TEST", "name": "Renamed", "deletion_protection": true}); err != nil {
+		t.Fatalf("unexpected error for an in-place update: %s", err)
+	}
+
+	err := diff(map[string]interface{}{"key": "OTHER", "name": "Test", "deletion_protection": true})
+	if err == nil || !strings.Contains(err.Error(), "deletion_protection") {
+		t.Fatalf("expected a deletion_protection error, got %v", err)
+	}
+
+	// disabling the protection in the same plan does not allow the replacement
+	err = diff(map[string]interface{}{"key": "OTHER", "name": "Test", "deletion_protection": false})
+	if err == nil {
+		t.Fatal("expected an error when disabling the protection along with the replacement")
+	}
+}
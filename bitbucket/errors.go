@@ -0,0 +1,113 @@
+package bitbucket
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/hashicorp/terraform/helper/resource"
+	"github.com/hashicorp/terraform/helper/schema"
+	"github.com/testOrgNataichi/terraform-provider-bitbucketserver/bitbucket/redact"
+)
+
+// ErrorDetail is a single error reported by the bitbucket api.
+type ErrorDetail struct {
+	// Context is the name of the field the error relates to, if any.
+	Context string `json:"context,omitempty"`
+	Message string `json:"message,omitempty"`
+	// ExceptionName is the fully qualified name of the server side exception,
+	// e.g. com.atlassian.bitbucket.project.NoSuchProjectException.
+	ExceptionName string `json:"exceptionName,omitempty"`
+}
+
+// Error represents a error from the bitbucket api.
+type Error struct {
+	Errors     []ErrorDetail `json:"errors,omitempty"`
+	StatusCode int
+	Endpoint   string
+}
+
+func (e Error) Error() string {
+
+	var errorMessages = ""
+	if e.Errors != nil {
+		for _, err := range e.Errors {
+			errorMessages += err.Message + "\n"
+		}
+	}
+
+	return fmt.Sprintf("API Error: %d %s %s", e.StatusCode, redact.URL(e.Endpoint), errorMessages)
+}
+
+// HasException reports whether the server raised an exception of the given name, which may be
+// the simple or fully qualified class name.
+func (e Error) HasException(name string) bool {
+	for _, err := range e.Errors {
+		if err.ExceptionName == name || strings.HasSuffix(err.ExceptionName, "."+name) {
+			return true
+		}
+	}
+	return false
+}
+
+// IsNotFound reports whether err is an api error for an object that does not exist.
+func IsNotFound(err error) bool {
+	return hasStatusCode(err, http.StatusNotFound)
+}
+
+// IsConflict reports whether err is an api error for a request conflicting with the current
+// state of an object, e.g. creating an object that already exists.
+func IsConflict(err error) bool {
+	return hasStatusCode(err, http.StatusConflict)
+}
+
+// IsUnauthorized reports whether err is an api error for a request with missing or invalid
+// credentials.
+func IsUnauthorized(err error) bool {
+	return hasStatusCode(err, http.StatusUnauthorized)
+}
+
+// IsForbidden reports whether err is an api error for a request the user lacks permissions for.
+func IsForbidden(err error) bool {
+	return hasStatusCode(err, http.StatusForbidden)
+}
+
+func hasStatusCode(err error, statusCode int) bool {
+	var apiError Error
+	return errors.As(err, &apiError) && apiError.StatusCode == statusCode
+}
+
+// ignoreNotFound treats an api error for an object that does not exist as success, for deletes
+// of objects that are already gone, e.g. along with a repository that was renamed or moved.
+func ignoreNotFound(err error) error {
+	if IsNotFound(err) {
+		return nil
+	}
+	return err
+}
+
+// removeIfNotFound removes the resource from state if err reports that its object no longer
+// exists, so the next plan recreates it instead of failing. Any other error is returned as-is.
+func removeIfNotFound(d *schema.ResourceData, err error) error {
+	if !IsNotFound(err) {
+		return err
+	}
+
+	log.Printf("[WARN] %s not found, removing from state", d.Id())
+	d.SetId("")
+	return nil
+}
+
+// retryUnlessCancelled retries a failed attempt of a resource.Retry loop with the waiting error,
+// unless the request was cancelled or timed out. Terraform cancels the client context when it is
+// interrupted, and every further attempt would fail the same way until the loop times out.
+func retryUnlessCancelled(client *BitbucketClient, err error, waiting error) *resource.RetryError {
+	if (client.Context != nil && client.Context.Err() != nil) ||
+		errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return resource.NonRetryableError(err)
+	}
+	return resource.RetryableError(waiting)
+}
@@ -0,0 +1,66 @@
+package bitbucket
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestError_Helpers(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		fmt.Fprint(w, `{"errors": [{"context": null, "message": "Project TEST does not exist.", "exceptionName": "com.atlassian.bitbucket.project.NoSuchProjectException"}]}`)
+	}))
+	defer server.Close()
+
+	client := &BitbucketClient{Server: server.URL, Token: "token", HTTPClient: server.Client()}
+
+	_, err := client.Get("/rest/api/1.0/projects/TEST")
+	if !IsNotFound(err) {
+		t.Fatalf("expected a not found error, got %v", err)
+	}
+	if IsConflict(err) || IsForbidden(err) {
+		t.Fatalf("unexpected error classification of %v", err)
+	}
+
+	apiError := err.(Error)
+	if !apiError.HasException("NoSuchProjectException") || !apiError.HasException("com.atlassian.bitbucket.project.NoSuchProjectException") {
+		t.Fatalf("expected NoSuchProjectException, got %+v", apiError.Errors)
+	}
+
+	if !IsNotFound(fmt.Errorf("reading project: %w", err)) {
+		t.Fatal("expected wrapped errors to be classified")
+	}
+}
+
+func TestError_Decode(t *testing.T) {
+	var apiError Error
+	err := json.Unmarshal([]byte(`{"errors": [{"context": "name", "message": "A project with this name already exists.", "exceptionName": "com.atlassian.bitbucket.validation.ArgumentValidationException"}]}`), &apiError)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	if apiError.Errors[0].Context != "name" || apiError.Errors[0].ExceptionName != "com.atlassian.bitbucket.validation.ArgumentValidationException" {
+		t.Fatalf("unexpected error details %+v", apiError.Errors)
+	}
+}
+
+func TestRemoveIfNotFound(t *testing.T) {
+	d := resourceProject().TestResourceData()
+	d.SetId("TEST")
+
+	if err := removeIfNotFound(d, Error{StatusCode: http.StatusNotFound}); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if d.Id() != "" {
+		t.Fatal("expected resource to be removed from state")
+	}
+
+	d.SetId("TEST")
+	forbidden := Error{StatusCode: http.StatusForbidden}
+	if err := removeIfNotFound(d, forbidden); err == nil || d.Id() != "TEST" {
+		t.Fatalf("expected forbidden error to be returned and state kept, got %v", err)
+	}
+}
@@ -0,0 +1,12 @@
+package bitbucket
+
+import (
+	"net/http/httptest"
+)
+
+// newTestProvider returns a provider whose client sends its requests to the test server.
+func newTestProvider(server *httptest.Server) *BitbucketServerProvider {
+	return &BitbucketServerProvider{
+		BitbucketClient: &BitbucketClient{Server: server.URL, Token: "token", HTTPClient: server.Client()},
+	}
+}
@@ -2,6 +2,7 @@ package marketplace
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -9,6 +10,11 @@ import (
 	"log"
 	"net/http"
 	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/testOrgNataichi/terraform-provider-bitbucketserver/bitbucket/redact"
 )
 
 // Error represents a error from the marketplace api.
@@ -19,71 +25,123 @@ type Error struct {
 
 func (e Error) Error() string {
 	var errorMessages = ""
-	return fmt.Sprintf("Marketplace Error: %d %s %s", e.StatusCode, e.Endpoint, errorMessages)
+	return fmt.Sprintf("Marketplace Error: %d %s %s", e.StatusCode, redact.URL(e.Endpoint), errorMessages)
 }
 
 const marketplaceServer = "https://marketplace.atlassian.com"
 
 type Client struct {
+	// Server is the base URL of the Marketplace API, defaults to the Atlassian Marketplace.
+	Server string
+	// CacheDir is an optional local directory where downloaded artifacts are kept.
+	CacheDir   string
 	HTTPClient *http.Client
+	// Context is the parent context of all requests, cancelling it aborts requests in flight.
+	Context context.Context
+	// RequestTimeout bounds the duration of a request including its retries and, for artifacts,
+	// the download of the body. 0 means no timeout.
+	RequestTimeout time.Duration
+}
+
+// requestContext returns the context of a single request, the returned function must be called
+// once the response body has been consumed.
+func (c *Client) requestContext() (context.Context, context.CancelFunc) {
+	ctx := c.Context
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if c.RequestTimeout > 0 {
+		return context.WithTimeout(ctx, c.RequestTimeout)
+	}
+	return context.WithCancel(ctx)
+}
+
+func (c *Client) server() string {
+	if c.Server != "" {
+		return strings.TrimSuffix(c.Server, "/")
+	}
+	return marketplaceServer
+}
+
+// ResolveURL turns links returned by the Marketplace API relative to the server into absolute URLs.
+func (c *Client) ResolveURL(link string) string {
+	if strings.HasPrefix(link, "/") {
+		return c.server() + link
+	}
+	return link
 }
 
 func (c *Client) Do(method, endpoint string, payload *bytes.Buffer) (*http.Response, error) {
 
-	absoluteendpoint := marketplaceServer + endpoint
-	log.Printf("[DEBUG] Sending request to %s %s", method, absoluteendpoint)
+	absoluteendpoint := c.server() + endpoint
+	log.Printf("[DEBUG] Sending request to %s %s", method, redact.URL(absoluteendpoint))
 
 	var bodyreader io.Reader
 
 	if payload != nil {
-		log.Printf("[DEBUG] With payload %s", payload.String())
+		log.Printf("[DEBUG] With payload %s", redact.Body(payload.Bytes()))
 		bodyreader = payload
 	}
 
-	req, err := http.NewRequest(method, absoluteendpoint, bodyreader)
+	ctx, cancel := c.requestContext()
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, method, absoluteendpoint, bodyreader)
 	if err != nil {
 		return nil, err
 	}
 
 	req.Header.Add("Content-Type", "application/json")
-	req.Close = true
 
 	resp, err := c.HTTPClient.Do(req)
-	log.Printf("[DEBUG] Resp: %v Err: %v", resp, err)
-	if resp != nil && (resp.StatusCode >= 400 || resp.StatusCode < 200) {
+	log.Printf("[DEBUG] Resp: %s Err: %v", redact.Response(resp), err)
+	if err != nil {
+		return resp, err
+	}
+
+	// buffer the body so the connection is returned to the pool even when callers never close it
+	body, err := ioutil.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = ioutil.NopCloser(bytes.NewReader(body))
+
+	if resp.StatusCode >= 400 || resp.StatusCode < 200 {
 		apiError := Error{
 			StatusCode: resp.StatusCode,
 			Endpoint:   endpoint,
 		}
 
-		body, err := ioutil.ReadAll(resp.Body)
-		if err != nil {
-			return nil, err
-		}
-
-		log.Printf("[DEBUG] Resp Body: %s", string(body))
+		log.Printf("[DEBUG] Resp Body: %s", redact.Body(body))
 
 		_ = json.Unmarshal(body, &apiError)
 		return resp, error(apiError)
-
 	}
-	return resp, err
+
+	return resp, nil
 }
 
-func (c *Client) DownloadArtifact(url string, dest *os.File) error {
+func (c *Client) DownloadArtifact(url string, dest io.Writer) error {
+
+	log.Printf("[DEBUG] Downloading file from %s", redact.URL(url))
 
-	log.Printf("[DEBUG] Downloading file from %s", url)
+	ctx, cancel := c.requestContext()
+	defer cancel()
 
-	req, err := http.NewRequest("GET", url, nil)
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
 	if err != nil {
 		return err
 	}
 
-	req.Close = true
-
 	resp, err := c.HTTPClient.Do(req)
-	log.Printf("[DEBUG] Resp: %v Err: %v", resp, err)
-	if resp != nil && (resp.StatusCode >= 400 || resp.StatusCode < 200) {
+	log.Printf("[DEBUG] Resp: %s Err: %v", redact.Response(resp), err)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 400 || resp.StatusCode < 200 {
 		apiError := Error{
 			StatusCode: resp.StatusCode,
 			Endpoint:   url,
@@ -94,7 +152,7 @@ func (c *Client) DownloadArtifact(url string, dest *os.File) error {
 			return err
 		}
 
-		log.Printf("[DEBUG] Resp Body: %s", string(body))
+		log.Printf("[DEBUG] Resp Body: %s", redact.Body(body))
 
 		_ = json.Unmarshal(body, &apiError)
 		return error(apiError)
@@ -109,6 +167,45 @@ func (c *Client) DownloadArtifact(url string, dest *os.File) error {
 	return nil
 }
 
+// CachedArtifact returns the path of the artifact in the cache directory, downloading it first
+// if it has not been cached yet.
+func (c *Client) CachedArtifact(url string, filename string) (string, error) {
+	path := filepath.Join(c.CacheDir, filename)
+	if _, err := os.Stat(path); err == nil {
+		log.Printf("[DEBUG] Using cached artifact %s", path)
+		return path, nil
+	}
+
+	err := os.MkdirAll(c.CacheDir, 0755)
+	if err != nil {
+		return "", err
+	}
+
+	// download to a temporary file first so an interrupted download never ends up in the cache
+	tmp, err := ioutil.TempFile(c.CacheDir, filename+".*.tmp")
+	if err != nil {
+		return "", err
+	}
+
+	err = c.DownloadArtifact(c.ResolveURL(url), tmp)
+	closeErr := tmp.Close()
+	if err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		_ = os.Remove(tmp.Name())
+		return "", err
+	}
+
+	err = os.Rename(tmp.Name(), path)
+	if err != nil {
+		_ = os.Remove(tmp.Name())
+		return "", err
+	}
+
+	return path, nil
+}
+
 func (c *Client) Get(endpoint string) (*http.Response, error) {
 	return c.Do("GET", endpoint, nil)
 }
@@ -0,0 +1,81 @@
+package marketplace
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestClient_CachedArtifact(t *testing.T) {
+	downloads := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/download/plugin.jar" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		downloads++
+		_, _ = w.Write([]byte("jar content"))
+	}))
+	defer server.Close()
+
+	client := &Client{
+		Server:     server.URL,
+		CacheDir:   filepath.Join(t.TempDir(), "cache"),
+		HTTPClient: server.Client(),
+	}
+
+	for i := 0; i < 2; i++ {
+		path, err := client.CachedArtifact("/download/plugin.jar", "com.example.plugin-1.0.0.jar")
+		if err != nil {
+			t.Fatalf("err: %s", err)
+		}
+
+		content, err := ioutil.ReadFile(path)
+		if err != nil {
+			t.Fatalf("err: %s", err)
+		}
+		if string(content) != "jar content" {
+			t.Fatalf("unexpected artifact content %q", content)
+		}
+	}
+
+	if downloads != 1 {
+		t.Fatalf("expected artifact to be downloaded once, got %d downloads", downloads)
+	}
+
+	if _, err := client.CachedArtifact("/download/missing.jar", "missing.jar"); err == nil {
+		t.Fatal("expected error for missing artifact")
+	}
+
+	files, _ := ioutil.ReadDir(client.CacheDir)
+	if len(files) != 1 {
+		t.Fatalf("expected failed download to leave no files behind, got %d files", len(files))
+	}
+}
+
+func TestClient_RequestTimeout(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// a stalled mirror
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	}))
+	defer server.Close()
+	defer close(release)
+
+	client := &Client{Server: server.URL, HTTPClient: server.Client(), RequestTimeout: 50 * time.Millisecond}
+
+	if _, err := client.Get("/rest/2/addons/com.example.plugin"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected the request to time out, got %v", err)
+	}
+	if err := client.DownloadArtifact(server.URL+"/plugin.jar", ioutil.Discard); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected the download to time out, got %v", err)
+	}
+}
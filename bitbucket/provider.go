@@ -1,17 +1,24 @@
 package bitbucket
 
 import (
+	"context"
+	"fmt"
+	"math"
 	"net/http"
+	"net/url"
 	"strings"
+	"time"
 
 	"github.com/testOrgNataichi/terraform-provider-bitbucketserver/bitbucket/marketplace"
+	"github.com/testOrgNataichi/terraform-provider-bitbucketserver/bitbucket/transport"
 
 	"github.com/hashicorp/terraform/helper/schema"
+	"github.com/hashicorp/terraform/helper/validation"
 	"github.com/hashicorp/terraform/terraform"
 )
 
 func Provider() terraform.ResourceProvider {
-	return &schema.Provider{
+	provider := &schema.Provider{
 		Schema: map[string]*schema.Schema{
 			"server": {
 				Required:    true,
@@ -19,24 +26,161 @@ func Provider() terraform.ResourceProvider {
 				DefaultFunc: schema.EnvDefaultFunc("BITBUCKET_SERVER", nil),
 			},
 			"username": {
-				Required:    true,
-				Type:        schema.TypeString,
-				DefaultFunc: schema.EnvDefaultFunc("BITBUCKET_USERNAME", nil),
+				Optional:      true,
+				Type:          schema.TypeString,
+				DefaultFunc:   schema.EnvDefaultFunc("BITBUCKET_USERNAME", nil),
+				ConflictsWith: []string{"token"},
 			},
 			"password": {
+				Type:          schema.TypeString,
+				Optional:      true,
+				Sensitive:     true,
+				DefaultFunc:   schema.EnvDefaultFunc("BITBUCKET_PASSWORD", nil),
+				ConflictsWith: []string{"token"},
+			},
+			"token": {
+				Type:          schema.TypeString,
+				Optional:      true,
+				Sensitive:     true,
+				DefaultFunc:   schema.EnvDefaultFunc("BITBUCKET_TOKEN", nil),
+				ConflictsWith: []string{"username", "password"},
+			},
+			"max_retries": {
+				Type:         schema.TypeInt,
+				Optional:     true,
+				ValidateFunc: validation.IntAtLeast(0),
+				Default:      3,
+			},
+			"retry_wait_min": {
+				Type:         schema.TypeInt,
+				Optional:     true,
+				ValidateFunc: validation.IntAtLeast(0),
+				Default:      1,
+			},
+			"retry_wait_max": {
+				Type:         schema.TypeInt,
+				Optional:     true,
+				ValidateFunc: validation.IntAtLeast(0),
+				Default:      30,
+			},
+			"request_timeout": {
+				Type:         schema.TypeInt,
+				Optional:     true,
+				ValidateFunc: validation.IntAtLeast(0),
+				Default:      0,
+			},
+			"page_limit": {
+				Type:         schema.TypeInt,
+				Optional:     true,
+				ValidateFunc: validation.IntBetween(0, 1000),
+				Default:      0,
+			},
+			"max_idle_conns_per_host": {
+				Type:         schema.TypeInt,
+				Optional:     true,
+				ValidateFunc: validation.IntAtLeast(1),
+				Default:      10,
+			},
+			"max_concurrent_requests": {
+				Type:         schema.TypeInt,
+				Optional:     true,
+				ValidateFunc: validation.IntAtLeast(0),
+				Default:      0,
+			},
+			"requests_per_second": {
+				Type:         schema.TypeFloat,
+				Optional:     true,
+				ValidateFunc: validation.FloatBetween(0, math.MaxFloat64),
+				Default:      0,
+			},
+			"workzone_max_concurrent_requests": {
+				Type:         schema.TypeInt,
+				Optional:     true,
+				ValidateFunc: validation.IntAtLeast(0),
+				Default:      0,
+			},
+			"workzone_requests_per_second": {
+				Type:         schema.TypeFloat,
+				Optional:     true,
+				ValidateFunc: validation.FloatBetween(0, math.MaxFloat64),
+				Default:      0,
+			},
+			"upm_max_concurrent_requests": {
+				Type:         schema.TypeInt,
+				Optional:     true,
+				ValidateFunc: validation.IntAtLeast(0),
+				Default:      0,
+			},
+			"upm_requests_per_second": {
+				Type:         schema.TypeFloat,
+				Optional:     true,
+				ValidateFunc: validation.FloatBetween(0, math.MaxFloat64),
+				Default:      0,
+			},
+			"ca_cert": {
 				Type:        schema.TypeString,
-				Required:    true,
-				DefaultFunc: schema.EnvDefaultFunc("BITBUCKET_PASSWORD", nil),
+				Optional:    true,
+				DefaultFunc: schema.EnvDefaultFunc("BITBUCKET_CA_CERT", nil),
+			},
+			"client_cert": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				DefaultFunc: schema.EnvDefaultFunc("BITBUCKET_CLIENT_CERT", nil),
+			},
+			"client_key": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				Sensitive:   true,
+				DefaultFunc: schema.EnvDefaultFunc("BITBUCKET_CLIENT_KEY", nil),
+			},
+			"insecure_skip_verify": {
+				Type:        schema.TypeBool,
+				Optional:    true,
+				DefaultFunc: schema.EnvDefaultFunc("BITBUCKET_INSECURE_SKIP_VERIFY", false),
+			},
+			"proxy_url": {
+				Type:     schema.TypeString,
+				Optional: true,
+			},
+			"no_proxy": {
+				Type:     schema.TypeSet,
+				Optional: true,
+				Elem:     &schema.Schema{Type: schema.TypeString},
+				Set:      schema.HashString,
+			},
+			"protected_projects": {
+				Type:     schema.TypeSet,
+				Optional: true,
+				Elem: &schema.Schema{
+					Type:         schema.TypeString,
+					ValidateFunc: validateProjectPattern,
+				},
+				Set: schema.HashString,
+			},
+			"marketplace_proxy_url": {
+				Type:     schema.TypeString,
+				Optional: true,
+			},
+			"marketplace_url": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				DefaultFunc: schema.EnvDefaultFunc("BITBUCKET_MARKETPLACE_URL", "https://marketplace.atlassian.com"),
+			},
+			"plugin_cache_dir": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				DefaultFunc: schema.EnvDefaultFunc("BITBUCKET_PLUGIN_CACHE_DIR", nil),
 			},
 		},
-		ConfigureFunc: providerConfigure,
 		DataSourcesMap: map[string]*schema.Resource{
 			"bitbucketserver_application_properties":        dataSourceApplicationProperties(),
 			"bitbucketserver_cluster":                       dataSourceCluster(),
+			"bitbucketserver_current_user":                  dataSourceCurrentUser(),
 			"bitbucketserver_global_permissions_groups":     dataSourceGlobalPermissionsGroups(),
 			"bitbucketserver_global_permissions_users":      dataSourceGlobalPermissionsUsers(),
 			"bitbucketserver_groups":                        dataSourceGroups(),
 			"bitbucketserver_group_users":                   dataSourceGroupUsers(),
+			"bitbucketserver_marketplace_plugin":            dataSourceMarketplacePlugin(),
 			"bitbucketserver_plugin":                        dataSourcePlugin(),
 			"bitbucketserver_project_hooks":                 dataSourceProjectHooks(),
 			"bitbucketserver_project_permissions_groups":    dataSourceProjectPermissionsGroups(),
@@ -75,33 +219,172 @@ func Provider() terraform.ResourceProvider {
 			"bitbucketserver_workzone_automerge":            resourceWorkzoneAutoMerge(),
 		},
 	}
+
+	// requests are cancelled when Terraform is interrupted
+	provider.ConfigureFunc = func(d *schema.ResourceData) (interface{}, error) {
+		return providerConfigure(provider.StopContext(), d)
+	}
+
+	return provider
 }
 
 type BitbucketServerProvider struct {
 	BitbucketClient   *BitbucketClient
 	MarketplaceClient *marketplace.Client
+	// ServerInfo holds the version of the Bitbucket server, fetched once at configure time.
+	ServerInfo *ServerInfo
+	// ProtectedProjects holds the key patterns of projects whose repositories and the project
+	// itself may not be deleted.
+	ProtectedProjects []string
+	// readCache shares list reads between resources of the same scope within a run
+	readCache readCache
 }
 
-func providerConfigure(d *schema.ResourceData) (interface{}, error) {
+func providerConfigure(ctx context.Context, d *schema.ResourceData) (interface{}, error) {
 
 	serverSanitized := d.Get("server").(string)
 	if strings.HasSuffix(serverSanitized, "/") {
 		serverSanitized = serverSanitized[0 : len(serverSanitized)-1]
 	}
 
+	username := d.Get("username").(string)
+	password := d.Get("password").(string)
+	token := d.Get("token").(string)
+
+	if token != "" && (username != "" || password != "") {
+		return nil, fmt.Errorf("token cannot be used together with username and password")
+	}
+
+	if token == "" && (username == "" || password == "") {
+		return nil, fmt.Errorf("either token or both username and password must be set")
+	}
+
+	retryWaitMin := time.Duration(d.Get("retry_wait_min").(int)) * time.Second
+	retryWaitMax := time.Duration(d.Get("retry_wait_max").(int)) * time.Second
+	if retryWaitMax < retryWaitMin {
+		return nil, fmt.Errorf("retry_wait_max must not be lower than retry_wait_min")
+	}
+
+	tlsConfig, err := transport.NewTLSConfig(transport.TLSOptions{
+		CACert:             d.Get("ca_cert").(string),
+		ClientCert:         d.Get("client_cert").(string),
+		ClientKey:          d.Get("client_key").(string),
+		InsecureSkipVerify: d.Get("insecure_skip_verify").(bool),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	noProxy := stringArrayFromSchemaSet(d.Get("no_proxy").(*schema.Set))
+
+	proxyURL := d.Get("proxy_url").(string)
+	proxy, err := transport.NewProxyFunc(transport.ProxyOptions{
+		URL:     proxyURL,
+		NoProxy: noProxy,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	marketplaceProxyURL := d.Get("marketplace_proxy_url").(string)
+	if marketplaceProxyURL == "" {
+		marketplaceProxyURL = proxyURL
+	}
+	marketplaceProxy, err := transport.NewProxyFunc(transport.ProxyOptions{
+		URL:     marketplaceProxyURL,
+		NoProxy: noProxy,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	newRetryTransport := func(proxy func(*http.Request) (*url.URL, error)) *transport.RetryTransport {
+		return &transport.RetryTransport{
+			Base: &transport.LimitTransport{
+				Base: transport.NewTransport(transport.Options{
+					TLSConfig:           tlsConfig,
+					Proxy:               proxy,
+					MaxIdleConnsPerHost: d.Get("max_idle_conns_per_host").(int),
+				}),
+			},
+			MaxRetries: d.Get("max_retries").(int),
+			WaitMin:    retryWaitMin,
+			WaitMax:    retryWaitMax,
+		}
+	}
+
+	// the workzone and UPM budgets fall back to the core settings when not set
+	newLimiter := func(prefix string) *transport.Limiter {
+		maxConcurrent := d.Get(prefix + "max_concurrent_requests").(int)
+		if maxConcurrent == 0 {
+			maxConcurrent = d.Get("max_concurrent_requests").(int)
+		}
+		perSecond := d.Get(prefix + "requests_per_second").(float64)
+		if perSecond == 0 {
+			perSecond = d.Get("requests_per_second").(float64)
+		}
+		return transport.NewLimiter(maxConcurrent, perSecond)
+	}
+
 	b := &BitbucketClient{
 		Server:     serverSanitized,
-		Username:   d.Get("username").(string),
-		Password:   d.Get("password").(string),
-		HTTPClient: &http.Client{},
+		Username:   username,
+		Password:   password,
+		Token:      token,
+		HTTPClient: &http.Client{Transport: newRetryTransport(proxy)},
+		PageLimit:  d.Get("page_limit").(int),
+		Limiters: RequestLimiters{
+			Core:     newLimiter(""),
+			Workzone: newLimiter("workzone_"),
+			UPM:      newLimiter("upm_"),
+		},
+		Context:        ctx,
+		RequestTimeout: time.Duration(d.Get("request_timeout").(int)) * time.Second,
 	}
 
 	m := &marketplace.Client{
-		HTTPClient: &http.Client{},
+		Server:         d.Get("marketplace_url").(string),
+		CacheDir:       d.Get("plugin_cache_dir").(string),
+		HTTPClient:     &http.Client{Transport: newRetryTransport(marketplaceProxy)},
+		Context:        ctx,
+		RequestTimeout: time.Duration(d.Get("request_timeout").(int)) * time.Second,
+	}
+
+	authError := fmt.Errorf("authentication against %s failed, check the username and password or the token", serverSanitized)
+
+	applicationProperties, err := readApplicationProperties(b)
+	if IsUnauthorized(err) {
+		return nil, authError
+	}
+	if err != nil {
+		return nil, fmt.Errorf("unable to read the application properties of %s, check that server points to a Bitbucket server: %s", serverSanitized, err)
+	}
+
+	serverInfo, err := newServerInfo(applicationProperties)
+	if err != nil {
+		return nil, err
+	}
+
+	if token != "" {
+		if err := serverInfo.Require(CapabilityAccessTokens); err != nil {
+			return nil, fmt.Errorf("token authentication is not available: %s", err)
+		}
+	}
+
+	// the application properties are served to anonymous users, so probe the credentials
+	// explicitly to fail here rather than on whichever resource refreshes first
+	currentUser, err := readAuthenticatedUserName(b)
+	if IsUnauthorized(err) || (err == nil && currentUser == "") {
+		return nil, authError
+	}
+	if err != nil {
+		return nil, fmt.Errorf("unable to verify the credentials against %s: %s", serverSanitized, err)
 	}
 
 	return &BitbucketServerProvider{
 		BitbucketClient:   b,
 		MarketplaceClient: m,
+		ServerInfo:        serverInfo,
+		ProtectedProjects: stringArrayFromSchemaSet(d.Get("protected_projects").(*schema.Set)),
 	}, nil
 }
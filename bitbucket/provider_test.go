@@ -1,9 +1,14 @@
 package bitbucket
 
 import (
+	"context"
+	"fmt"
 	"github.com/hashicorp/terraform/helper/schema"
 	"github.com/hashicorp/terraform/terraform"
+	"net/http"
+	"net/http/httptest"
 	"os"
+	"strings"
 	"testing"
 )
 
@@ -34,11 +39,96 @@ func testAccPreCheck(t *testing.T) {
 		t.Fatal("BITBUCKET_SERVER must be set for acceptance tests")
 	}
 
+	if v := os.Getenv("BITBUCKET_TOKEN"); v != "" {
+		return
+	}
+
 	if v := os.Getenv("BITBUCKET_USERNAME"); v == "" {
-		t.Fatal("BITBUCKET_USERNAME must be set for acceptance tests")
+		t.Fatal("BITBUCKET_USERNAME or BITBUCKET_TOKEN must be set for acceptance tests")
 	}
 
 	if v := os.Getenv("BITBUCKET_PASSWORD"); v == "" {
-		t.Fatal("BITBUCKET_PASSWORD must be set for acceptance tests")
+		t.Fatal("BITBUCKET_PASSWORD or BITBUCKET_TOKEN must be set for acceptance tests")
+	}
+}
+
+func testBitbucketServer(whoami func(w http.ResponseWriter, r *http.Request)) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/rest/api/1.0/application-properties":
+			fmt.Fprint(w, `{"version": "7.21.0", "buildNumber": "7021000", "displayName": "Bitbucket"}`)
+		case "/plugins/servlet/applinks/whoami":
+			whoami(w, r)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+}
+
+func testProviderConfigure(t *testing.T, server string) (interface{}, error) {
+	d := schema.TestResourceDataRaw(t, Provider().(*schema.Provider).Schema, map[string]interface{}{
+		"server":      server,
+		"token":       "token",
+		"max_retries": 0,
+	})
+	return providerConfigure(context.Background(), d)
+}
+
+func TestProviderConfigure(t *testing.T) {
+	server := testBitbucketServer(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "admin")
+	})
+	defer server.Close()
+
+	meta, err := testProviderConfigure(t, server.URL)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	if version := meta.(*BitbucketServerProvider).ServerInfo.Version.String(); version != "7.21.0" {
+		t.Fatalf("unexpected server version %s", version)
+	}
+}
+
+func TestProviderConfigure_InvalidCredentials(t *testing.T) {
+	for name, whoami := range map[string]func(w http.ResponseWriter, r *http.Request){
+		"unauthorized": func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusUnauthorized) },
+		"anonymous":    func(w http.ResponseWriter, r *http.Request) {},
+	} {
+		server := testBitbucketServer(whoami)
+
+		_, err := testProviderConfigure(t, server.URL)
+		if err == nil || !strings.Contains(err.Error(), "authentication against") {
+			t.Errorf("%s: expected an authentication error, got %v", name, err)
+		}
+
+		server.Close()
+	}
+}
+
+func TestProviderConfigure_NotBitbucket(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	defer server.Close()
+
+	_, err := testProviderConfigure(t, server.URL)
+	if err == nil || !strings.Contains(err.Error(), "check that server points to a Bitbucket server") {
+		t.Fatalf("expected a server error, got %v", err)
+	}
+}
+
+func TestProviderConfigure_TokenConflictsWithBasicAuth(t *testing.T) {
+	os.Setenv("BITBUCKET_USERNAME", "admin")
+	os.Setenv("BITBUCKET_PASSWORD", "admin")
+	defer os.Unsetenv("BITBUCKET_USERNAME")
+	defer os.Unsetenv("BITBUCKET_PASSWORD")
+
+	server := testBitbucketServer(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "admin")
+	})
+	defer server.Close()
+
+	_, err := testProviderConfigure(t, server.URL)
+	if err == nil || !strings.Contains(err.Error(), "token cannot be used together with username and password") {
+		t.Fatalf("expected a conflict error, got %v", err)
 	}
 }
@@ -0,0 +1,56 @@
+package bitbucket
+
+import (
+	"sync"
+)
+
+// readCache memoises list reads for the lifetime of the provider instance, so resources sharing
+// a scope, such as the permissions of one project, fetch the list only once per run. Writes to a
+// scope need to invalidate it.
+type readCache struct {
+	mutex   sync.Mutex
+	entries map[string]*readCacheEntry
+}
+
+type readCacheEntry struct {
+	once  sync.Once
+	value interface{}
+	err   error
+}
+
+// get returns the cached value of the scope, calling fetch on the first access. Concurrent
+// callers for the same scope wait for a single fetch. Failed fetches are not cached.
+func (c *readCache) get(scope string, fetch func() (interface{}, error)) (interface{}, error) {
+	c.mutex.Lock()
+	if c.entries == nil {
+		c.entries = make(map[string]*readCacheEntry)
+	}
+	entry, ok := c.entries[scope]
+	if !ok {
+		entry = &readCacheEntry{}
+		c.entries[scope] = entry
+	}
+	c.mutex.Unlock()
+
+	entry.once.Do(func() {
+		entry.value, entry.err = fetch()
+	})
+
+	if entry.err != nil {
+		c.mutex.Lock()
+		if c.entries[scope] == entry {
+			delete(c.entries, scope)
+		}
+		c.mutex.Unlock()
+	}
+
+	return entry.value, entry.err
+}
+
+// invalidate drops the cached value of the scope. Fetches already in flight complete for their
+// callers but are not served to later reads.
+func (c *readCache) invalidate(scope string) {
+	c.mutex.Lock()
+	delete(c.entries, scope)
+	c.mutex.Unlock()
+}
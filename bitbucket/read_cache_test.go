@@ -0,0 +1,52 @@
+package bitbucket
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+)
+
+func TestReadCache(t *testing.T) {
+	var cache readCache
+
+	fetches := 0
+	fetch := func() (interface{}, error) {
+		fetches++
+		return fetches, nil
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := cache.get("projects/TEST/permissions/users", fetch); err != nil {
+				t.Errorf("err: %s", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if fetches != 1 {
+		t.Fatalf("expected a single fetch, got %d", fetches)
+	}
+
+	cache.invalidate("projects/TEST/permissions/users")
+
+	value, _ := cache.get("projects/TEST/permissions/users", fetch)
+	if value != 2 {
+		t.Fatalf("expected invalidated scope to be fetched again, got %v", value)
+	}
+
+	_, err := cache.get("projects/OTHER/permissions/users", func() (interface{}, error) {
+		return nil, fmt.Errorf("API Error: 500")
+	})
+	if err == nil {
+		t.Fatal("expected fetch error to be returned")
+	}
+
+	value, err = cache.get("projects/OTHER/permissions/users", fetch)
+	if err != nil || value != 3 {
+		t.Fatalf("expected failed fetch not to be cached, got %v %v", value, err)
+	}
+}
@@ -0,0 +1,112 @@
+// Package redact masks credentials and other secrets before requests and responses are logged.
+package redact
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// Mask replaces every redacted value.
+const Mask = "REDACTED"
+
+// sensitiveNames are matched case-insensitively against JSON keys, query parameters and headers.
+var sensitiveNames = []string{
+	"password",
+	"passwd",
+	"secret",
+	"token",
+	"license",
+	"credential",
+	"authorization",
+	"cookie",
+	"privatekey",
+}
+
+// IsSensitive reports whether a field, parameter or header of the given name holds a secret.
+func IsSensitive(name string) bool {
+	name = strings.ToLower(name)
+	for _, sensitive := range sensitiveNames {
+		if strings.Contains(name, sensitive) {
+			return true
+		}
+	}
+	return false
+}
+
+// Body masks the values of sensitive fields in a JSON document. Bodies that are not JSON are
+// returned unchanged.
+func Body(body []byte) string {
+	var document interface{}
+	if err := json.Unmarshal(body, &document); err != nil {
+		return string(body)
+	}
+
+	redacted, err := json.Marshal(redactValue(document))
+	if err != nil {
+		return string(body)
+	}
+	return string(redacted)
+}
+
+func redactValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, child := range v {
+			if IsSensitive(key) && child != nil {
+				v[key] = Mask
+			} else {
+				v[key] = redactValue(child)
+			}
+		}
+	case []interface{}:
+		for i, child := range v {
+			v[i] = redactValue(child)
+		}
+	}
+	return value
+}
+
+// URL masks the values of sensitive query parameters.
+func URL(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.RawQuery == "" {
+		return rawURL
+	}
+
+	query := u.Query()
+	redacted := false
+	for key := range query {
+		if IsSensitive(key) {
+			query[key] = []string{Mask}
+			redacted = true
+		}
+	}
+	if redacted {
+		u.RawQuery = query.Encode()
+	}
+	return u.String()
+}
+
+// Header returns a copy of the header with the values of sensitive headers masked.
+func Header(header http.Header) http.Header {
+	redacted := make(http.Header, len(header))
+	for key, values := range header {
+		if IsSensitive(key) {
+			redacted[key] = []string{Mask}
+		} else {
+			redacted[key] = values
+		}
+	}
+	return redacted
+}
+
+// Response summarises the status and redacted headers of a response.
+func Response(resp *http.Response) string {
+	if resp == nil {
+		return "<nil>"
+	}
+	return fmt.Sprintf("%s %v", resp.Status, Header(resp.Header))
+}
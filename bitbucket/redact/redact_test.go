@@ -0,0 +1,58 @@
+package redact
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestBody(t *testing.T) {
+	body := `{"hostname":"smtp.example.com","password":"hunter2","configuration":{"secret":"s3cr3t","url":"https://example.com"},"rawLicense":"AAAB","items":[{"token":"abc"}]}`
+
+	redacted := Body([]byte(body))
+	for _, secret := range []string{"hunter2", "s3cr3t", "AAAB", "abc"} {
+		if strings.Contains(redacted, secret) {
+			t.Errorf("secret %q not redacted: %s", secret, redacted)
+		}
+	}
+	for _, value := range []string{"smtp.example.com", "https://example.com"} {
+		if !strings.Contains(redacted, value) {
+			t.Errorf("value %q unexpectedly redacted: %s", value, redacted)
+		}
+	}
+}
+
+func TestBody_NotJSON(t *testing.T) {
+	if got := Body([]byte("plain text")); got != "plain text" {
+		t.Fatalf("unexpected body %q", got)
+	}
+}
+
+func TestURL(t *testing.T) {
+	got := URL("https://bitbucket.example.com/rest/api/1.0/admin/users?name=jdoe&password=hunter2")
+	if strings.Contains(got, "hunter2") || !strings.Contains(got, "name=jdoe") {
+		t.Fatalf("unexpected url %s", got)
+	}
+
+	unchanged := "https://bitbucket.example.com/rest/api/1.0/projects?limit=25"
+	if got := URL(unchanged); got != unchanged {
+		t.Fatalf("expected %s, got %s", unchanged, got)
+	}
+}
+
+func TestHeader(t *testing.T) {
+	header := http.Header{}
+	header.Set("Authorization", "Bearer abc")
+	header.Set("Content-Type", "application/json")
+
+	redacted := Header(header)
+	if redacted.Get("Authorization") != Mask {
+		t.Errorf("Authorization not redacted: %v", redacted)
+	}
+	if redacted.Get("Content-Type") != "application/json" {
+		t.Errorf("Content-Type unexpectedly redacted: %v", redacted)
+	}
+	if header.Get("Authorization") != "Bearer abc" {
+		t.Errorf("original header modified")
+	}
+}
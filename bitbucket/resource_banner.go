@@ -7,6 +7,8 @@ import (
 	"github.com/hashicorp/terraform/helper/schema"
 	"github.com/hashicorp/terraform/helper/validation"
 	"io/ioutil"
+	"log"
+	"net/http"
 )
 
 type Banner struct {
@@ -85,7 +87,14 @@ func resourceBannerRead(d *schema.ResourceData, m interface{}) error {
 	req, err := client.Get("/rest/api/1.0/admin/banner")
 
 	if err != nil {
-		return err
+		return removeIfNotFound(d, err)
+	}
+
+	// no banner is configured
+	if req.StatusCode == http.StatusNoContent {
+		log.Printf("[WARN] Banner not found, removing from state")
+		d.SetId("")
+		return nil
 	}
 
 	var banner Banner
@@ -110,6 +119,9 @@ func resourceBannerRead(d *schema.ResourceData, m interface{}) error {
 func resourceBannerExists(d *schema.ResourceData, m interface{}) (bool, error) {
 	client := m.(*BitbucketServerProvider).BitbucketClient
 	repoReq, err := client.Get("/rest/api/1.0/admin/banner")
+	if IsNotFound(err) {
+		return false, nil
+	}
 	if err != nil {
 		return false, fmt.Errorf("failed to get banner from bitbucket: %+v", err)
 	}
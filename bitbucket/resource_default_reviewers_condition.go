@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
+	"log"
 	"net/url"
 	"strconv"
 	"strings"
@@ -331,7 +332,7 @@ func resourceDefaultReviewersConditionRead(d *schema.ResourceData, m interface{}
 	resp, err := client.Get(getReadConditionURI(projectKey, repositorySlug))
 
 	if err != nil {
-		return err
+		return removeIfNotFound(d, err)
 	}
 
 	if resp.StatusCode == 200 {
@@ -351,14 +352,18 @@ func resourceDefaultReviewersConditionRead(d *schema.ResourceData, m interface{}
 
 		condition := selectConditionByID(conditions, conditionID)
 
-		if condition != nil {
-			d.Set("project_key", projectKey)
-			d.Set("repository_slug", repositorySlug)
-			d.Set("source_matcher", collapseMatcher(refMatcherToMatcher(condition.SourceRefMatcher)))
-			d.Set("target_matcher", collapseMatcher(refMatcherToMatcher(condition.TargetRefMatcher)))
-			d.Set("reviewers", collapseReviewers(condition.Reviewers))
-			d.Set("required_approvals", condition.RequiredApprovals)
+		if condition == nil {
+			log.Printf("[WARN] Default reviewers condition %s not found, removing from state", d.Id())
+			d.SetId("")
+			return nil
 		}
+
+		d.Set("project_key", projectKey)
+		d.Set("repository_slug", repositorySlug)
+		d.Set("source_matcher", collapseMatcher(refMatcherToMatcher(condition.SourceRefMatcher)))
+		d.Set("target_matcher", collapseMatcher(refMatcherToMatcher(condition.TargetRefMatcher)))
+		d.Set("reviewers", collapseReviewers(condition.Reviewers))
+		d.Set("required_approvals", condition.RequiredApprovals)
 	}
 
 	return nil
@@ -375,7 +380,7 @@ func resourceDefaultReviewersConditionExists(d *schema.ResourceData, m interface
 
 	resp, err := client.Get(getReadConditionURI(projectKey, repositorySlug))
 
-	if resp != nil && resp.StatusCode == 404 {
+	if IsNotFound(err) {
 		return false, nil
 	}
 
@@ -413,5 +418,5 @@ func resourceDefaultReviewersConditionDelete(d *schema.ResourceData, m interface
 
 	_, err = client.Delete(getDeleteConditionURI(conditionID, projectKey, repositorySlug))
 
-	return err
+	return ignoreNotFound(err)
 }
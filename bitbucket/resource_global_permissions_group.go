@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"github.com/hashicorp/terraform/helper/schema"
 	"github.com/hashicorp/terraform/helper/validation"
+	"log"
 	"net/url"
 )
 
@@ -72,10 +73,12 @@ func resourceGlobalPermissionsGroupRead(d *schema.ResourceData, m interface{}) e
 	for _, g := range groups {
 		if g.Name == group {
 			_ = d.Set("permission", g.Permission)
-			break
+			return nil
 		}
 	}
 
+	log.Printf("[WARN] Global permission of group %s not found, removing from state", group)
+	d.SetId("")
 	return nil
 }
 
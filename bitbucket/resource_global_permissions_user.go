@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"github.com/hashicorp/terraform/helper/schema"
 	"github.com/hashicorp/terraform/helper/validation"
+	"log"
 	"net/url"
 )
 
@@ -71,11 +72,13 @@ func resourceGlobalPermissionsUserRead(d *schema.ResourceData, m interface{}) er
 	// API only filters but we need to find an exact match
 	for _, g := range users {
 		if g.Name == user {
-			d.Set("permission", g.Permission)
-			break
+			_ = d.Set("permission", g.Permission)
+			return nil
 		}
 	}
 
+	log.Printf("[WARN] Global permission of user %s not found, removing from state", user)
+	d.SetId("")
 	return nil
 }
 
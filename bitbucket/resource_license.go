@@ -119,7 +119,7 @@ func resourceLicenseRead(d *schema.ResourceData, m interface{}) error {
 	req, err := client.Get("/rest/api/1.0/admin/license")
 
 	if err != nil {
-		return err
+		return removeIfNotFound(d, err)
 	}
 
 	if req.StatusCode == 200 {
@@ -115,7 +115,7 @@ func resourceMailServerRead(d *schema.ResourceData, m interface{}) error {
 	req, err := client.Get("/rest/api/1.0/admin/mail-server")
 
 	if err != nil {
-		return err
+		return removeIfNotFound(d, err)
 	}
 
 	if req.StatusCode == 200 {
@@ -2,15 +2,21 @@ package bitbucket
 
 import (
 	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
+	"io"
 	"io/ioutil"
+	"os"
 	"path/filepath"
 	"regexp"
+	"strings"
 	"time"
 
 	"github.com/hashicorp/terraform/helper/resource"
 	"github.com/hashicorp/terraform/helper/schema"
+	"github.com/hashicorp/terraform/helper/validation"
 )
 
 type Plugin struct {
@@ -61,7 +67,9 @@ type PluginMarketplaceVersion struct {
 	} `json:"_links,omitempty"`
 	Embedded struct {
 		Artifact struct {
-			Links struct {
+			// Sha256 is only published by some marketplace implementations
+			Sha256 string `json:"sha256,omitempty"`
+			Links  struct {
 				Self struct {
 					Href string `json:"href,omitempty"`
 				} `json:"self,omitempty"`
@@ -71,6 +79,93 @@ type PluginMarketplaceVersion struct {
 			} `json:"_links,omitempty"`
 		} `json:"artifact,omitempty"`
 	} `json:"_embedded,omitempty"`
+	Compatibilities []PluginMarketplaceCompatibility `json:"compatibilities,omitempty"`
+	Release         struct {
+		Date string `json:"date,omitempty"`
+	} `json:"release,omitempty"`
+	Deployment struct {
+		Server           bool   `json:"server,omitempty"`
+		DataCenter       bool   `json:"dataCenter,omitempty"`
+		DataCenterStatus string `json:"dataCenterStatus,omitempty"`
+	} `json:"deployment,omitempty"`
+}
+
+type PluginMarketplaceCompatibility struct {
+	Application string `json:"application,omitempty"`
+	Hosting     struct {
+		Server     *PluginMarketplaceBuildRange `json:"server,omitempty"`
+		DataCenter *PluginMarketplaceBuildRange `json:"dataCenter,omitempty"`
+	} `json:"hosting,omitempty"`
+}
+
+type PluginMarketplaceBuildRange struct {
+	Min PluginMarketplaceBuild `json:"min,omitempty"`
+	Max PluginMarketplaceBuild `json:"max,omitempty"`
+}
+
+type PluginMarketplaceBuild struct {
+	Build   int    `json:"build,omitempty"`
+	Version string `json:"version,omitempty"`
+}
+
+func (r *PluginMarketplaceBuildRange) contains(build int) bool {
+	return r != nil && build >= r.Min.Build && build <= r.Max.Build
+}
+
+func (r *PluginMarketplaceBuildRange) String() string {
+	return fmt.Sprintf("%s - %s", r.Min.Version, r.Max.Version)
+}
+
+func (p *PluginMarketplaceVersion) bitbucketCompatibilities() []PluginMarketplaceCompatibility {
+	var compatibilities []PluginMarketplaceCompatibility
+	for _, compatibility := range p.Compatibilities {
+		if compatibility.Application == "bitbucket" {
+			compatibilities = append(compatibilities, compatibility)
+		}
+	}
+	return compatibilities
+}
+
+// SupportsBuild reports whether the version is compatible with the given Bitbucket build number,
+// either as server or as data center app. Versions without compatibility information are
+// assumed to be compatible.
+func (p *PluginMarketplaceVersion) SupportsBuild(build int) bool {
+	compatibilities := p.bitbucketCompatibilities()
+	if len(compatibilities) == 0 {
+		return true
+	}
+
+	for _, compatibility := range compatibilities {
+		if compatibility.Hosting.Server.contains(build) || compatibility.Hosting.DataCenter.contains(build) {
+			return true
+		}
+	}
+	return false
+}
+
+// DataCenterApproved reports whether the version is an approved data center app. Marketplaces
+// that do not publish a status are trusted on the deployment flag alone.
+func (p *PluginMarketplaceVersion) DataCenterApproved() bool {
+	status := p.Deployment.DataCenterStatus
+	return p.Deployment.DataCenter && (status == "" || status == "approved")
+}
+
+// CompatibleVersions describes the Bitbucket versions supported by the version.
+func (p *PluginMarketplaceVersion) CompatibleVersions() string {
+	var ranges []string
+	for _, compatibility := range p.bitbucketCompatibilities() {
+		if compatibility.Hosting.Server != nil {
+			ranges = append(ranges, fmt.Sprintf("server %s", compatibility.Hosting.Server))
+		}
+		if compatibility.Hosting.DataCenter != nil {
+			ranges = append(ranges, fmt.Sprintf("data center %s", compatibility.Hosting.DataCenter))
+		}
+	}
+
+	if len(ranges) == 0 {
+		return "none"
+	}
+	return strings.Join(ranges, ", ")
 }
 
 func (p *PluginMarketplaceVersion) Key() string {
@@ -82,9 +177,15 @@ func (p *PluginMarketplaceVersion) Key() string {
 	return ""
 }
 
-func (p *PluginMarketplaceVersion) Filename() string {
+// Filename is the name of the artifact of the plugin in the cache. The key is taken from the
+// resource rather than parsed from the self link, which mirrors do not always shape like the
+// marketplace does.
+func (p *PluginMarketplaceVersion) Filename(key string) string {
 	ext := filepath.Ext(p.Embedded.Artifact.Links.Self.Href)
-	return fmt.Sprintf("%s-%s%s", p.Key(), p.Version, ext)
+	if ext == "" {
+		ext = ".jar"
+	}
+	return fmt.Sprintf("%s-%s%s", key, p.Version, ext)
 }
 
 func resourcePlugin() *schema.Resource {
@@ -97,6 +198,11 @@ func resourcePlugin() *schema.Resource {
 		Importer: &schema.ResourceImporter{
 			State: schema.ImportStatePassthrough,
 		},
+		CustomizeDiff: resourcePluginCustomizeDiff,
+		Timeouts: &schema.ResourceTimeout{
+			Create: schema.DefaultTimeout(2 * time.Minute),
+			Update: schema.DefaultTimeout(2 * time.Minute),
+		},
 
 		Schema: map[string]*schema.Schema{
 			"key": {
@@ -105,9 +211,31 @@ func resourcePlugin() *schema.Resource {
 				ForceNew: true,
 			},
 			"version": {
+				Type:          schema.TypeString,
+				Optional:      true,
+				Computed:      true,
+				ConflictsWith: []string{"source_file", "source_url"},
+			},
+			"source_file": {
+				Type:          schema.TypeString,
+				Optional:      true,
+				ForceNew:      true,
+				ConflictsWith: []string{"source_url"},
+			},
+			"source_url": {
+				Type:          schema.TypeString,
+				Optional:      true,
+				ForceNew:      true,
+				ConflictsWith: []string{"source_file"},
+			},
+			"source_hash": {
 				Type:     schema.TypeString,
-				Required: true,
-				ForceNew: true,
+				Computed: true,
+			},
+			"sha256": {
+				Type:         schema.TypeString,
+				Optional:     true,
+				ValidateFunc: validation.StringMatch(regexp.MustCompile("^[a-fA-F0-9]{64}$"), "must be a hex encoded SHA-256"),
 			},
 			"enabled": {
 				Type:     schema.TypeBool,
@@ -256,34 +384,24 @@ func resourcePluginCreate(d *schema.ResourceData, m interface{}) error {
 	provider := m.(*BitbucketServerProvider)
 
 	key := d.Get("key").(string)
-	version := d.Get("version").(string)
-
-	marketplacePluginVersion, err := readMarketplacePluginVersion(key, version, provider)
-	if err != nil {
-		return err
-	}
 
-	// first get a token for interacting with the UPM
-	resp, err := provider.BitbucketClient.Get("/rest/plugins/1.0/?os_authType=basic")
-	if err != nil {
-		return err
-	}
-	upmToken := resp.Header.Get("upm-token")
+	// both wait loops share the create timeout
+	deadline := time.Now().Add(d.Timeout(schema.TimeoutCreate))
 
-	pluginUri := marketplacePluginVersion.Embedded.Artifact.Links.Binary.Href
-
-	// now we can use the token to install plugin to Bitbucket
-	_, err = provider.BitbucketClient.InstallPluginWithUri("/rest/plugins/1.0/?token="+upmToken, pluginUri, d.Get("key").(string))
+	err := installPlugin(d, provider)
 	if err != nil {
 		return err
 	}
 
 	d.SetId(key)
 
-	err = resource.Retry(time.Minute*2,
+	err = resource.Retry(time.Until(deadline),
 		func() *resource.RetryError {
 			exists, err := resourcePluginExists(d, m)
-			if exists == false || err != nil {
+			if err != nil {
+				return retryUnlessCancelled(provider.BitbucketClient, err, fmt.Errorf("Waiting for plugin installation to finish..."))
+			}
+			if exists == false {
 				return resource.RetryableError(fmt.Errorf("Waiting for plugin installation to finish..."))
 			} else {
 				return nil
@@ -294,11 +412,11 @@ func resourcePluginCreate(d *schema.ResourceData, m interface{}) error {
 	}
 
 	// need to also run an update loop to set enabled flags and license details
-	err = resource.Retry(time.Minute*2,
+	err = resource.Retry(time.Until(deadline),
 		func() *resource.RetryError {
 			err := resourcePluginUpdate(d, m)
 			if err != nil {
-				return resource.RetryableError(fmt.Errorf("Waiting for plugin updates to finish..."))
+				return retryUnlessCancelled(provider.BitbucketClient, err, fmt.Errorf("Waiting for plugin updates to finish..."))
 			} else {
 				return nil
 			}
@@ -310,16 +428,300 @@ func resourcePluginCreate(d *schema.ResourceData, m interface{}) error {
 	return nil
 }
 
+// installPlugin installs the plugin artifact configured on the resource through the UPM, either
+// from a local file, an arbitrary URL or the marketplace.
+func installPlugin(d *schema.ResourceData, provider *BitbucketServerProvider) error {
+	client := provider.BitbucketClient
+
+	key := d.Get("key").(string)
+	version := d.Get("version").(string)
+	sourceFile := d.Get("source_file").(string)
+	sourceURL := d.Get("source_url").(string)
+
+	if sourceFile == "" && sourceURL == "" && version == "" {
+		return fmt.Errorf("one of version, source_file or source_url must be set for plugin %s", key)
+	}
+
+	var marketplacePluginVersion *PluginMarketplaceVersion
+	if sourceFile == "" && sourceURL == "" {
+		var err error
+		marketplacePluginVersion, err = readMarketplacePluginVersion(key, version, provider)
+		if err != nil {
+			return err
+		}
+	}
+
+	// first get a token for interacting with the UPM
+	resp, err := client.Get(client.upmEndpoint("/rest/plugins/1.0/"))
+	if err != nil {
+		return err
+	}
+	upmEndpoint := "/rest/plugins/1.0/?token=" + resp.Header.Get("upm-token")
+
+	// artifacts passing through the provider are verified before they are handed to the UPM
+	checksum := d.Get("sha256").(string)
+	expectedChecksums := []string{checksum}
+
+	// now we can use the token to install plugin to Bitbucket
+	if sourceFile != "" {
+		return uploadPluginArtifact(client, upmEndpoint, sourceFile, expectedChecksums)
+	}
+
+	if sourceURL != "" {
+		hash, err := installPluginFromURL(provider, upmEndpoint, key, sourceURL, checksum)
+		if err != nil {
+			return err
+		}
+		return d.Set("source_hash", hash)
+	}
+
+	pluginUri := provider.MarketplaceClient.ResolveURL(marketplacePluginVersion.Embedded.Artifact.Links.Binary.Href)
+	expectedChecksums = append(expectedChecksums, marketplacePluginVersion.Embedded.Artifact.Sha256)
+
+	if provider.MarketplaceClient.CacheDir != "" {
+		// with a local cache the artifact is downloaded by the provider and uploaded to the UPM,
+		// so the Bitbucket server itself never needs to reach the marketplace
+		path, err := provider.MarketplaceClient.CachedArtifact(pluginUri, marketplacePluginVersion.Filename(key))
+		if err != nil {
+			return err
+		}
+
+		err = uploadPluginArtifact(client, upmEndpoint, path, expectedChecksums)
+		if _, ok := err.(checksumError); ok {
+			// never keep a corrupted artifact in the cache
+			_ = os.Remove(path)
+		}
+		return err
+	}
+
+	if checksum != "" {
+		_, err = downloadAndUploadPluginArtifact(provider, upmEndpoint, pluginUri, expectedChecksums)
+		return err
+	}
+
+	_, err = client.InstallPluginWithUri(upmEndpoint, pluginUri, key)
+	return err
+}
+
+type checksumError struct {
+	Path     string
+	Expected string
+	Actual   string
+}
+
+func (e checksumError) Error() string {
+	return fmt.Sprintf("checksum mismatch for plugin artifact %s: expected sha256 %s, got %s", filepath.Base(e.Path), e.Expected, e.Actual)
+}
+
+// uploadPluginArtifact verifies the artifact against all non-empty expected checksums and
+// uploads it to the UPM.
+func uploadPluginArtifact(client *BitbucketClient, upmEndpoint string, path string, expectedChecksums []string) error {
+	actual, err := fileSha256(path)
+	if err != nil {
+		return err
+	}
+
+	for _, expected := range expectedChecksums {
+		if expected != "" && !strings.EqualFold(expected, actual) {
+			return checksumError{Path: path, Expected: expected, Actual: actual}
+		}
+	}
+
+	_, err = client.PostFileUpload(upmEndpoint, nil, "plugin", path)
+	return err
+}
+
+// installPluginFromURL downloads the artifact of a source_url, verifies it against the sha256 it is
+// pinned by and uploads it to the UPM. Artifacts are kept in the artifact cache under their
+// checksum. It returns the SHA-256 of the uploaded artifact.
+func installPluginFromURL(provider *BitbucketServerProvider, upmEndpoint string, key string, url string, checksum string) (string, error) {
+	if checksum == "" {
+		return "", fmt.Errorf("sha256 must be set along with source_url for plugin %s", key)
+	}
+	if provider.MarketplaceClient.CacheDir == "" {
+		return downloadAndUploadPluginArtifact(provider, upmEndpoint, url, []string{checksum})
+	}
+
+	checksum = strings.ToLower(checksum)
+	path, err := provider.MarketplaceClient.CachedArtifact(url, fmt.Sprintf("%s-%s%s", key, checksum, pluginArtifactExt(url)))
+	if err != nil {
+		return "", err
+	}
+
+	err = uploadPluginArtifact(provider.BitbucketClient, upmEndpoint, path, []string{checksum})
+	if _, ok := err.(checksumError); ok {
+		// never keep a corrupted artifact in the cache
+		_ = os.Remove(path)
+	}
+	if err != nil {
+		return "", err
+	}
+	return checksum, nil
+}
+
+func pluginArtifactExt(url string) string {
+	if filepath.Ext(url) == ".obr" {
+		return ".obr"
+	}
+	return ".jar"
+}
+
+// downloadAndUploadPluginArtifact downloads the artifact to a temporary file and uploads it to the
+// UPM. It returns the SHA-256 of the uploaded artifact.
+func downloadAndUploadPluginArtifact(provider *BitbucketServerProvider, upmEndpoint string, url string, expectedChecksums []string) (string, error) {
+	tmp, err := ioutil.TempFile("", "bitbucketserver-plugin-*"+pluginArtifactExt(url))
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(tmp.Name())
+
+	err = provider.MarketplaceClient.DownloadArtifact(url, tmp)
+	closeErr := tmp.Close()
+	if err != nil {
+		return "", err
+	}
+	if closeErr != nil {
+		return "", closeErr
+	}
+
+	hash, err := fileSha256(tmp.Name())
+	if err != nil {
+		return "", err
+	}
+
+	return hash, uploadPluginArtifact(provider.BitbucketClient, upmEndpoint, tmp.Name(), expectedChecksums)
+}
+
+func fileSha256(path string) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	hash := sha256.New()
+	_, err = io.Copy(hash, file)
+	if err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// checkPluginCompatibility fails when the marketplace version does not support the build of the
+// Bitbucket server the plugin would be installed to.
+func checkPluginCompatibility(marketplaceVersion *PluginMarketplaceVersion, key string, provider *BitbucketServerProvider) error {
+	serverInfo, err := provider.serverInfo()
+	if err != nil {
+		return err
+	}
+
+	if !marketplaceVersion.SupportsBuild(serverInfo.BuildNumber) {
+		return fmt.Errorf("plugin %s version %s is not compatible with Bitbucket %s (build %d), supported Bitbucket versions: %s",
+			key,
+			marketplaceVersion.Version,
+			serverInfo.Version,
+			serverInfo.BuildNumber,
+			marketplaceVersion.CompatibleVersions(),
+		)
+	}
+
+	return nil
+}
+
+func resourcePluginCustomizeDiff(d *schema.ResourceDiff, m interface{}) error {
+	provider := m.(*BitbucketServerProvider)
+	key := d.Get("key").(string)
+	version := d.Get("version").(string)
+	sourceFile := d.Get("source_file").(string)
+	sourceURL := d.Get("source_url").(string)
+
+	if !d.NewValueKnown("source_file") || !d.NewValueKnown("source_url") {
+		return d.SetNewComputed("source_hash")
+	}
+
+	// validate marketplace versions at plan time rather than waiting for the UPM to reject them
+	if sourceFile == "" && sourceURL == "" && version != "" && d.NewValueKnown("version") && (d.Id() == "" || d.HasChange("version")) {
+		marketplaceVersion, err := readMarketplacePluginVersion(key, version, provider)
+		if err != nil {
+			return err
+		}
+
+		err = checkPluginCompatibility(marketplaceVersion, key, provider)
+		if err != nil {
+			return err
+		}
+	}
+
+	// the planned hash is the one of a source_file or the sha256 pinning a source_url, URL artifacts
+	// are never downloaded at plan time
+	hash := ""
+	if sourceFile != "" {
+		var err error
+		hash, err = fileSha256(sourceFile)
+		if err != nil {
+			return err
+		}
+	} else if sourceURL != "" {
+		checksum := d.Get("sha256").(string)
+		if !d.NewValueKnown("sha256") {
+			return d.SetNewComputed("source_hash")
+		}
+		if checksum == "" {
+			// the content behind a URL can change at any time, only a pinned hash detects that
+			return fmt.Errorf("sha256 must be set along with source_url for plugin %s, so that a changed artifact is reinstalled", key)
+		}
+		hash = strings.ToLower(checksum)
+	}
+
+	old := d.Get("source_hash").(string)
+	if hash == old {
+		return nil
+	}
+
+	err := d.SetNew("source_hash", hash)
+	if err != nil {
+		return err
+	}
+
+	// a changed artifact is reinstalled, imported plugins only record the hash
+	if d.Id() != "" && old != "" {
+		return d.ForceNew("source_hash")
+	}
+
+	return nil
+}
+
 func resourcePluginUpdate(d *schema.ResourceData, m interface{}) error {
 	client := m.(*BitbucketServerProvider).BitbucketClient
 
 	key := d.Get("key").(string)
 
-	if d.IsNewResource() || d.HasChange("enabled") {
+	// installing a different version on top of the existing plugin is an upgrade in the UPM,
+	// which keeps the plugin configuration and license in place
+	upgraded := false
+	if !d.IsNewResource() && d.HasChange("version") {
+		oldVersion, newVersion := d.GetChange("version")
+
+		err := installPlugin(d, m.(*BitbucketServerProvider))
+		if err != nil {
+			return fmt.Errorf("failed to upgrade plugin %s from version %s to %s, the installed version was left in place: %s", key, oldVersion, newVersion, err)
+		}
+
+		// the UPM accepted the artifact, so the upgrade may still complete after the timeout
+		err = waitForPluginVersion(client, key, newVersion.(string), d.Timeout(schema.TimeoutUpdate))
+		if err != nil {
+			return fmt.Errorf("upgrade of plugin %s from version %s to %s did not finish within the update timeout, it may still be in progress: %s", key, oldVersion, newVersion, err)
+		}
+
+		upgraded = true
+	}
+
+	if d.IsNewResource() || d.HasChange("enabled") || upgraded {
 		var plugin Plugin
-		req, err := client.Do("GET", fmt.Sprintf("/rest/plugins/1.0/%s-key?os_authType=basic", key), nil, "application/vnd.atl.plugins.plugin+json")
+		req, err := client.Do("GET", client.upmEndpoint(fmt.Sprintf("/rest/plugins/1.0/%s-key", key)), nil, "application/vnd.atl.plugins.plugin+json")
 		if err != nil {
-			return nil
+			return err
 		}
 
 		body, readErr := ioutil.ReadAll(req.Body)
@@ -334,7 +736,10 @@ func resourcePluginUpdate(d *schema.ResourceData, m interface{}) error {
 
 		plugin.Enabled = d.Get("enabled").(bool)
 		bytedata, err := json.Marshal(plugin)
-		_, err = client.Do("PUT", fmt.Sprintf("/rest/plugins/1.0/%s-key?os_authType=basic", key), bytes.NewBuffer(bytedata), "application/vnd.atl.plugins.plugin+json")
+		if err != nil {
+			return err
+		}
+		_, err = client.Do("PUT", client.upmEndpoint(fmt.Sprintf("/rest/plugins/1.0/%s-key", key)), bytes.NewBuffer(bytedata), "application/vnd.atl.plugins.plugin+json")
 		if err != nil {
 			return err
 		}
@@ -349,14 +754,14 @@ func resourcePluginUpdate(d *schema.ResourceData, m interface{}) error {
 				return err
 			}
 
-			req, err := client.Do("PUT", fmt.Sprintf("/rest/plugins/1.0/%s-key/license?os_authType=basic", key), bytes.NewBuffer(bytedata), "application/vnd.atl.plugins+json")
+			req, err := client.Do("PUT", client.upmEndpoint(fmt.Sprintf("/rest/plugins/1.0/%s-key/license", key)), bytes.NewBuffer(bytedata), "application/vnd.atl.plugins+json")
 
 			// ignore 400 errors as this happens if the license is already applied
 			if req == nil || (err != nil && req != nil && req.StatusCode != 400) {
 				return err
 			}
 		} else {
-			_, err := client.Do("DELETE", fmt.Sprintf("/rest/plugins/1.0/%s-key/license?os_authType=basic", key), nil, "application/vnd.atl.plugins+json")
+			_, err := client.Do("DELETE", client.upmEndpoint(fmt.Sprintf("/rest/plugins/1.0/%s-key/license", key)), nil, "application/vnd.atl.plugins+json")
 			if err != nil {
 				return err
 			}
@@ -366,6 +771,27 @@ func resourcePluginUpdate(d *schema.ResourceData, m interface{}) error {
 	return resourcePluginRead(d, m)
 }
 
+func waitForPluginVersion(client *BitbucketClient, key string, version string, timeout time.Duration) error {
+	return resource.Retry(timeout,
+		func() *resource.RetryError {
+			req, err := client.Get(fmt.Sprintf("/rest/plugins/1.0/%s-key", key))
+			if err != nil {
+				return retryUnlessCancelled(client, err, err)
+			}
+
+			var plugin Plugin
+			decodeErr := json.NewDecoder(req.Body).Decode(&plugin)
+			if decodeErr != nil {
+				return resource.NonRetryableError(decodeErr)
+			}
+
+			if plugin.Version != version {
+				return resource.RetryableError(fmt.Errorf("Waiting for plugin upgrade to %s to finish, found %s...", version, plugin.Version))
+			}
+			return nil
+		})
+}
+
 func resourcePluginRead(d *schema.ResourceData, m interface{}) error {
 	id := d.Id()
 	if id != "" {
@@ -375,7 +801,7 @@ func resourcePluginRead(d *schema.ResourceData, m interface{}) error {
 	client := m.(*BitbucketServerProvider).BitbucketClient
 	req, err := client.Get(fmt.Sprintf("/rest/plugins/1.0/%s-key", d.Get("key").(string)))
 	if err != nil {
-		return err
+		return removeIfNotFound(d, err)
 	}
 
 	var plugin Plugin
@@ -465,6 +891,9 @@ func resourcePluginExists(d *schema.ResourceData, m interface{}) (bool, error) {
 		key,
 	))
 
+	if IsNotFound(err) {
+		return false, nil
+	}
 	if err != nil {
 		return false, fmt.Errorf("failed to get plugin %s from bitbucket: %+v", key, err)
 	}
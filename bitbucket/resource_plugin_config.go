@@ -69,7 +69,7 @@ func resourcePluginConfigRead(d *schema.ResourceData, m interface{}) error {
 
 	pluginConfig, err := readPluginConfig(m, configEndpoint)
 	if err != nil {
-		return err
+		return removeIfNotFound(d, err)
 	}
 
 	err = d.Set("values", pluginConfig.Values)
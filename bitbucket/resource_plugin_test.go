@@ -1,9 +1,25 @@
 package bitbucket
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"regexp"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/testOrgNataichi/terraform-provider-bitbucketserver/bitbucket/marketplace"
+
+	"github.com/hashicorp/terraform/config"
 	"github.com/hashicorp/terraform/helper/resource"
+	"github.com/hashicorp/terraform/helper/schema"
+	"github.com/hashicorp/terraform/terraform"
 )
 
 func TestAccBitbucketPlugin_install(t *testing.T) {
@@ -57,3 +73,291 @@ func TestAccBitbucketPlugin_install(t *testing.T) {
 		},
 	})
 }
+
+func TestAccBitbucketPlugin_sourceConflictsWithVersion(t *testing.T) {
+	config := `
+		resource "bitbucketserver_plugin" "test" {
+			key        = "com.plugin.commitgraph.commitgraph"
+			version    = "5.3.3"
+			source_url = "https://plugins.example.com/commitgraph-5.3.3.jar"
+		}
+	`
+
+	resource.Test(t, resource.TestCase{
+		PreCheck:  func() { testAccPreCheck(t) },
+		Providers: testAccProviders,
+		Steps: []resource.TestStep{
+			{
+				Config:      config,
+				ExpectError: regexp.MustCompile("conflicts with"),
+			},
+		},
+	})
+}
+
+func TestPluginMarketplaceVersion_Filename(t *testing.T) {
+	var version PluginMarketplaceVersion
+	// a mirror self link that does not follow the marketplace layout
+	err := json.Unmarshal([]byte(`{
+		"name": "1.2.0",
+		"_links": {"self": {"href": "/mirror/plugins/my_plugin/1.2.0"}},
+		"_embedded": {"artifact": {"_links": {"self": {"href": "/mirror/files/my_plugin-1.2.0.obr"}}}}
+	}`), &version)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	if filename := version.Filename("com.example.my_plugin"); filename != "com.example.my_plugin-1.2.0.obr" {
+		t.Fatalf("unexpected filename %s", filename)
+	}
+}
+
+func TestPluginMarketplaceVersion_SupportsBuild(t *testing.T) {
+	var version PluginMarketplaceVersion
+	err := json.Unmarshal([]byte(`{
+		"name": "7.5.3",
+		"compatibilities": [
+			{"application": "jira", "hosting": {"server": {"min": {"build": 1, "version": "1.0.0"}, "max": {"build": 99999999, "version": "99.0.0"}}}},
+			{"application": "bitbucket", "hosting": {
+				"server": {"min": {"build": 6000000, "version": "6.0.0"}, "max": {"build": 7006000, "version": "7.6.0"}},
+				"dataCenter": {"min": {"build": 6000000, "version": "6.0.0"}, "max": {"build": 7010000, "version": "7.10.0"}}
+			}}
+		]
+	}`), &version)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	cases := map[int]bool{
+		5016000: false,
+		6000000: true,
+		7008000: true,
+		7010000: true,
+		7011000: false,
+	}
+	for build, expected := range cases {
+		if actual := version.SupportsBuild(build); actual != expected {
+			t.Errorf("build %d: expected %t, got %t", build, expected, actual)
+		}
+	}
+
+	expectedVersions := "server 6.0.0 - 7.6.0, data center 6.0.0 - 7.10.0"
+	if actual := version.CompatibleVersions(); actual != expectedVersions {
+		t.Errorf("expected %q, got %q", expectedVersions, actual)
+	}
+
+	if !(&PluginMarketplaceVersion{}).SupportsBuild(7011000) {
+		t.Error("expected versions without compatibility information to be compatible")
+	}
+}
+
+func TestUploadPluginArtifact_ChecksumMismatch(t *testing.T) {
+	uploads := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		uploads++
+	}))
+	defer server.Close()
+
+	client := newTestProvider(server).BitbucketClient
+
+	path := filepath.Join(t.TempDir(), "plugin.jar")
+	if err := ioutil.WriteFile(path, []byte("jar content"), 0600); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	// sha256 of an empty artifact
+	checksum := "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+	actual, err := fileSha256(path)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	err = uploadPluginArtifact(client, "/rest/plugins/1.0/", path, []string{checksum})
+	if _, ok := err.(checksumError); !ok {
+		t.Fatalf("expected checksum error, got %v", err)
+	}
+	if uploads != 0 {
+		t.Fatal("expected artifact with mismatching checksum not to be uploaded")
+	}
+
+	err = uploadPluginArtifact(client, "/rest/plugins/1.0/", path, []string{"", actual})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if uploads != 1 {
+		t.Fatalf("expected artifact to be uploaded once, got %d uploads", uploads)
+	}
+}
+
+func TestResourcePluginDiff_SourceURLNotDownloaded(t *testing.T) {
+	downloads := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		downloads++
+	}))
+	defer server.Close()
+
+	checksum := "E3B0C44298FC1C149AFBF4C8996FB92427AE41E4649B934CA495991B7852B855"
+	rawConfig, err := config.NewRawConfig(map[string]interface{}{
+		"key":        "com.example.plugin",
+		"source_url": server.URL + "/plugin.jar",
+		"sha256":     checksum,
+	})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	provider := &BitbucketServerProvider{MarketplaceClient: &marketplace.Client{HTTPClient: server.Client()}}
+	diff, err := resourcePlugin().Diff(nil, terraform.NewResourceConfig(rawConfig), provider)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	if downloads != 0 {
+		t.Fatalf("expected no download at plan time, got %d", downloads)
+	}
+	if hash := diff.Attributes["source_hash"].New; hash != strings.ToLower(checksum) {
+		t.Fatalf("expected the pinned sha256 as source_hash, got %q", hash)
+	}
+}
+
+func TestResourcePluginDiff_SourceURLRequiresSha256(t *testing.T) {
+	rawConfig, err := config.NewRawConfig(map[string]interface{}{
+		"key":        "com.example.plugin",
+		"source_url": "https://plugins.example.com/plugin.jar",
+	})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	_, err = resourcePlugin().Diff(nil, terraform.NewResourceConfig(rawConfig), &BitbucketServerProvider{})
+	if err == nil || !strings.Contains(err.Error(), "sha256 must be set along with source_url") {
+		t.Fatalf("expected a missing sha256 error, got %v", err)
+	}
+}
+
+func TestInstallPluginFromURL_Cache(t *testing.T) {
+	artifact := []byte("jar content")
+	sum := sha256.Sum256(artifact)
+	checksum := hex.EncodeToString(sum[:])
+
+	downloads, uploads := 0, 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/plugin.jar":
+			downloads++
+			_, _ = w.Write(artifact)
+		case "/rest/plugins/1.0/":
+			uploads++
+		default:
+			t.Errorf("unexpected request %s %s", r.Method, r.URL)
+		}
+	}))
+	defer server.Close()
+
+	provider := newTestProvider(server)
+	provider.MarketplaceClient = &marketplace.Client{HTTPClient: server.Client(), CacheDir: t.TempDir()}
+
+	for i := 0; i < 2; i++ {
+		hash, err := installPluginFromURL(provider, "/rest/plugins/1.0/", "com.example.plugin", server.URL+"/plugin.jar", strings.ToUpper(checksum))
+		if err != nil {
+			t.Fatalf("err: %s", err)
+		}
+		if hash != checksum {
+			t.Fatalf("expected hash %s, got %s", checksum, hash)
+		}
+	}
+
+	if downloads != 1 || uploads != 2 {
+		t.Fatalf("expected the cached artifact to be uploaded twice from one download, got %d downloads and %d uploads", downloads, uploads)
+	}
+}
+
+func TestResourcePluginUpdate_Upgrade(t *testing.T) {
+	cases := map[string]struct {
+		rejectInstall bool
+		neverUpgrades bool
+		failRead      bool
+		err           string
+	}{
+		"upgrade":           {},
+		"upgrade rejected":  {rejectInstall: true, err: "the installed version was left in place"},
+		"upgrade times out": {neverUpgrades: true, err: "did not finish within the update timeout"},
+		"plugin read fails": {failRead: true, err: "500"},
+	}
+
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			var installed string
+			pluginReads := 0
+			var server *httptest.Server
+			server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				switch {
+				case r.URL.Path == "/rest/2/addons/com.example.plugin/versions/name/2.0.0":
+					fmt.Fprintf(w, `{"name": "2.0.0", "_embedded": {"artifact": {"_links": {"binary": {"href": "%s/plugin-2.0.0.jar"}}}}}`, server.URL)
+				case r.Method == "GET" && r.URL.Path == "/rest/plugins/1.0/":
+					w.Header().Set("upm-token", "upm")
+				case r.Method == "POST" && r.URL.Path == "/rest/plugins/1.0/":
+					if c.rejectInstall {
+						w.WriteHeader(http.StatusBadRequest)
+						return
+					}
+					var payload PluginInstallPayload
+					_ = json.NewDecoder(r.Body).Decode(&payload)
+					installed = payload.PluginURI
+					w.WriteHeader(http.StatusAccepted)
+				case r.Method == "GET" && r.URL.Path == "/rest/plugins/1.0/com.example.plugin-key":
+					// the first read is the wait for the upgrade, the second the enabled state
+					pluginReads++
+					if c.failRead && pluginReads > 1 {
+						w.WriteHeader(http.StatusInternalServerError)
+						return
+					}
+					if c.neverUpgrades {
+						fmt.Fprint(w, `{"key": "com.example.plugin", "version": "1.0.0", "enabled": true}`)
+						return
+					}
+					fmt.Fprint(w, `{"key": "com.example.plugin", "version": "2.0.0", "enabled": true}`)
+				case r.Method == "PUT" && r.URL.Path == "/rest/plugins/1.0/com.example.plugin-key":
+				case r.Method == "GET" && r.URL.Path == "/rest/plugins/1.0/com.example.plugin-key/license":
+					fmt.Fprint(w, `{}`)
+				default:
+					t.Errorf("unexpected request %s %s", r.Method, r.URL)
+				}
+			}))
+			defer server.Close()
+
+			provider := newTestProvider(server)
+			provider.MarketplaceClient = &marketplace.Client{Server: server.URL, HTTPClient: server.Client()}
+
+			state := &terraform.InstanceState{
+				ID:         "com.example.plugin",
+				Attributes: map[string]string{"id": "com.example.plugin", "key": "com.example.plugin", "version": "1.0.0", "enabled": "true"},
+			}
+			diff := &terraform.InstanceDiff{
+				Attributes: map[string]*terraform.ResourceAttrDiff{"version": {Old: "1.0.0", New: "2.0.0"}},
+			}
+			timeout := time.Second
+			if err := (&schema.ResourceTimeout{Update: &timeout}).DiffEncode(diff); err != nil {
+				t.Fatalf("err: %s", err)
+			}
+
+			state, err := resourcePlugin().Apply(state, diff, provider)
+			if c.err != "" {
+				if err == nil || !strings.Contains(err.Error(), c.err) {
+					t.Fatalf("expected an error containing %q, got %v", c.err, err)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("err: %s", err)
+			}
+			if installed != server.URL+"/plugin-2.0.0.jar" {
+				t.Fatalf("expected version 2.0.0 to be installed, got %q", installed)
+			}
+			if version := state.Attributes["version"]; version != "2.0.0" {
+				t.Fatalf("unexpected version %s", version)
+			}
+		})
+	}
+}
@@ -5,8 +5,6 @@ import (
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
-	"log"
-	"net/http"
 	"net/url"
 	"strings"
 
@@ -177,13 +175,7 @@ func resourcePrSettingsRead(d *schema.ResourceData, m interface{}) error {
 	))
 
 	if err != nil {
-		return err
-	}
-
-	if req.StatusCode == http.StatusNotFound {
-		log.Printf("[WARN] Workzone Reviewers object (%s) not found, removing from state", d.Id())
-		d.SetId("")
-		return nil
+		return removeIfNotFound(d, err)
 	}
 
 	var settings PrSettings
@@ -227,7 +219,7 @@ func resourcePrSettingsDelete(d *schema.ResourceData, m interface{}) error {
 		url.QueryEscape(repository),
 	), bytes.NewBuffer(bytedata))
 
-	return err
+	return ignoreNotFound(err)
 }
 
 func expandMergeConfig(l []interface{}) MergeConfig {
@@ -4,8 +4,13 @@ import (
 	"bytes"
 	"encoding/json"
 	"fmt"
-	"github.com/hashicorp/terraform/helper/schema"
 	"io/ioutil"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/terraform/helper/resource"
+	"github.com/hashicorp/terraform/helper/schema"
 )
 
 type Project struct {
@@ -26,6 +31,10 @@ func resourceProject() *schema.Resource {
 		Importer: &schema.ResourceImporter{
 			State: schema.ImportStatePassthrough,
 		},
+		CustomizeDiff: resourceProjectCustomizeDiff,
+		Timeouts: &schema.ResourceTimeout{
+			Delete: schema.DefaultTimeout(5 * time.Minute),
+		},
 
 		Schema: map[string]*schema.Schema{
 			"name": {
@@ -50,6 +59,16 @@ func resourceProject() *schema.Resource {
 				Type:     schema.TypeString,
 				Optional: true,
 			},
+			"deletion_protection": {
+				Type:     schema.TypeBool,
+				Optional: true,
+				Default:  false,
+			},
+			"force_destroy": {
+				Type:     schema.TypeBool,
+				Optional: true,
+				Default:  false,
+			},
 		},
 	}
 }
@@ -122,7 +141,7 @@ func resourceProjectRead(d *schema.ResourceData, m interface{}) error {
 	))
 
 	if err != nil {
-		return err
+		return removeIfNotFound(d, err)
 	}
 
 	if project_req.StatusCode == 200 {
@@ -162,6 +181,9 @@ func resourceProjectExists(d *schema.ResourceData, m interface{}) (bool, error)
 		project,
 	))
 
+	if IsNotFound(err) {
+		return false, nil
+	}
 	if err != nil {
 		return false, fmt.Errorf("failed to get project %s from bitbucket: %+v", project, err)
 	}
@@ -173,12 +195,71 @@ func resourceProjectExists(d *schema.ResourceData, m interface{}) (bool, error)
 	}
 }
 
+// resourceProjectCustomizeDiff refuses to replace protected projects.
+func resourceProjectCustomizeDiff(d *schema.ResourceDiff, m interface{}) error {
+	return checkReplacementProtection(d, m, resourceProject().Schema, "project "+d.Id(), d.Id())
+}
+
 func resourceProjectDelete(d *schema.ResourceData, m interface{}) error {
 	project := d.Get("key").(string)
+
+	err := checkDeletionProtection(m, "project "+project, project, d.Get("deletion_protection").(bool))
+	if err != nil {
+		return err
+	}
+
 	client := m.(*BitbucketServerProvider).BitbucketClient
-	_, err := client.Delete(fmt.Sprintf("/rest/api/1.0/projects/%s",
-		project,
-	))
+	if !d.Get("force_destroy").(bool) {
+		_, err = client.Delete(fmt.Sprintf("/rest/api/1.0/projects/%s",
+			project,
+		))
+
+		return err
+	}
+
+	removed, err := deleteProjectRepositories(client, project)
+	if len(removed) > 0 {
+		log.Printf("[WARN] force_destroy removed the repositories of project %s: %s", project, strings.Join(removed, ", "))
+	}
+	if err != nil {
+		return fmt.Errorf("force_destroy of project %s failed after removing the repositories [%s]: %s", project, strings.Join(removed, ", "), err)
+	}
+
+	// Bitbucket deletes repositories in the background, the project conflicts until they are gone
+	return resource.Retry(d.Timeout(schema.TimeoutDelete), func() *resource.RetryError {
+		_, err := client.Delete(fmt.Sprintf("/rest/api/1.0/projects/%s",
+			project,
+		))
+		if IsConflict(err) {
+			return resource.RetryableError(err)
+		}
+		if err != nil {
+			return resource.NonRetryableError(err)
+		}
+		return nil
+	})
+}
+
+// deleteProjectRepositories deletes every repository of the project and returns the slugs of the
+// removed repositories, also when it fails part way.
+func deleteProjectRepositories(client *BitbucketClient, project string) ([]string, error) {
+	var repositories []Repository
+	err := client.GetAllPages(fmt.Sprintf("/rest/api/1.0/projects/%s/repos", project), nil, &repositories)
+	if err != nil {
+		return nil, err
+	}
+
+	removed := make([]string, 0, len(repositories))
+	for _, repository := range repositories {
+		_, err := client.Delete(fmt.Sprintf("/rest/api/1.0/projects/%s/repos/%s",
+			project,
+			repository.Slug,
+		))
+		if ignoreNotFound(err) != nil {
+			return removed, err
+		}
+		removed = append(removed, repository.Slug)
+	}
 
-	return err
+	return removed, nil
 }
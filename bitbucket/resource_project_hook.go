@@ -93,7 +93,7 @@ func resourceProjectHookRead(d *schema.ResourceData, m interface{}) error {
 	))
 
 	if err != nil {
-		return err
+		return removeIfNotFound(d, err)
 	}
 
 	var settings map[string]interface{}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"github.com/hashicorp/terraform/helper/schema"
 	"github.com/hashicorp/terraform/helper/validation"
+	"log"
 	"net/url"
 	"strings"
 )
@@ -45,6 +46,7 @@ func resourceProjectPermissionsUserUpdate(d *schema.ResourceData, m interface{})
 		url.QueryEscape(d.Get("permission").(string)),
 		url.QueryEscape(d.Get("user").(string)),
 	), nil)
+	m.(*BitbucketServerProvider).readCache.invalidate(projectPermissionsUsersScope(d.Get("project").(string)))
 
 	if err != nil {
 		return err
@@ -76,19 +78,21 @@ func resourceProjectPermissionsUserRead(d *schema.ResourceData, m interface{}) e
 	}
 
 	user := d.Get("user").(string)
-	users, err := readProjectPermissionsUsers(m, d.Get("project").(string), user)
+	users, err := readCachedProjectPermissionsUsers(m, d.Get("project").(string))
 	if err != nil {
-		return err
+		return removeIfNotFound(d, err)
 	}
 
-	// API only filters but we need to find an exact match
+	// the cached list holds every user of the project, find the exact match
 	for _, g := range users {
 		if g.Name == user {
 			d.Set("permission", g.Permission)
-			break
+			return nil
 		}
 	}
 
+	log.Printf("[WARN] Project permission %s not found, removing from state", d.Id())
+	d.SetId("")
 	return nil
 }
 
@@ -98,6 +102,7 @@ func resourceProjectPermissionsUserDelete(d *schema.ResourceData, m interface{})
 		d.Get("project").(string),
 		url.QueryEscape(d.Get("user").(string)),
 	))
+	m.(*BitbucketServerProvider).readCache.invalidate(projectPermissionsUsersScope(d.Get("project").(string)))
 
-	return err
+	return ignoreNotFound(err)
 }
@@ -3,11 +3,14 @@ package bitbucket
 import (
 	"fmt"
 	"math/rand"
+	"net/http"
+	"net/http/httptest"
 	"strings"
 	"testing"
 	"time"
 
 	"github.com/hashicorp/terraform/helper/resource"
+	"github.com/hashicorp/terraform/helper/schema"
 	"github.com/hashicorp/terraform/terraform"
 )
 
@@ -77,3 +80,50 @@ func testAccCheckBitbucketProjectExists(n string) resource.TestCheckFunc {
 		return nil
 	}
 }
+
+func TestResourceProjectDelete_ForceDestroy(t *testing.T) {
+	var deleted []string
+	projectDeletes := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == "GET" && r.URL.Path == "/rest/api/1.0/projects/TEST/repos" && r.URL.Query().Get("start") == "":
+			fmt.Fprint(w, `{"values": [{"slug": "one"}, {"slug": "two"}], "isLastPage": false, "start": 0, "nextPageStart": 2}`)
+		case r.Method == "GET" && r.URL.Path == "/rest/api/1.0/projects/TEST/repos":
+			fmt.Fprint(w, `{"values": [{"slug": "three"}], "isLastPage": true, "start": 2}`)
+		case r.Method == "DELETE" && strings.HasPrefix(r.URL.Path, "/rest/api/1.0/projects/TEST/repos/"):
+			deleted = append(deleted, strings.TrimPrefix(r.URL.Path, "/rest/api/1.0/projects/TEST/repos/"))
+			w.WriteHeader(http.StatusAccepted)
+		case r.Method == "DELETE" && r.URL.Path == "/rest/api/1.0/projects/TEST":
+			// the repositories are still being deleted on the first attempt
+			projectDeletes++
+			if projectDeletes == 1 {
+				w.WriteHeader(http.StatusConflict)
+				return
+			}
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			t.Errorf("unexpected request %s %s", r.Method, r.URL)
+		}
+	}))
+	defer server.Close()
+
+	provider := newTestProvider(server)
+
+	d := schema.TestResourceDataRaw(t, resourceProject().Schema, map[string]interface{}{
+		"key":           "TEST",
+		"name":          "Test",
+		"force_destroy": true,
+	})
+	d.SetId("TEST")
+
+	if err := resourceProjectDelete(d, provider); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	if strings.Join(deleted, ",") != "one,two,three" {
+		t.Fatalf("unexpected deleted repositories %v", deleted)
+	}
+	if projectDeletes != 2 {
+		t.Fatalf("expected the project delete to be retried once, got %d attempts", projectDeletes)
+	}
+}
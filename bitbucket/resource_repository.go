@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
+	"net/http"
 
 	"github.com/hashicorp/terraform/helper/schema"
 	"strings"
@@ -16,16 +17,24 @@ type CloneUrl struct {
 }
 
 type Repository struct {
-	Name        string `json:"name,omitempty"`
-	Slug        string `json:"slug,omitempty"`
-	Description string `json:"description,omitempty"`
-	Forkable    bool   `json:"forkable"`
-	Public      bool   `json:"public,omitempty"`
+	Name        string                 `json:"name,omitempty"`
+	Slug        string                 `json:"slug,omitempty"`
+	Description string                 `json:"description,omitempty"`
+	Forkable    bool                   `json:"forkable"`
+	Public      bool                   `json:"public,omitempty"`
+	Archived    *bool                  `json:"archived,omitempty"`
+	Project     *RepositoryForkProject `json:"project,omitempty"`
 	Links       struct {
 		Clone []CloneUrl `json:"clone,omitempty"`
 	} `json:"links,omitempty"`
 }
 
+// RepositoryBranch is a branch reference as sent to and returned by the default branch endpoints.
+type RepositoryBranch struct {
+	ID        string `json:"id,omitempty"`
+	DisplayID string `json:"displayId,omitempty"`
+}
+
 type RepositoryForkProject struct {
 	Key string `json:"key,omitempty"`
 }
@@ -45,12 +54,12 @@ func resourceRepository() *schema.Resource {
 		Importer: &schema.ResourceImporter{
 			State: schema.ImportStatePassthrough,
 		},
+		CustomizeDiff: resourceRepositoryCustomizeDiff,
 
 		Schema: map[string]*schema.Schema{
 			"name": {
 				Type:     schema.TypeString,
 				Required: true,
-				ForceNew: true,
 			},
 			"slug": {
 				Type:     schema.TypeString,
@@ -60,7 +69,6 @@ func resourceRepository() *schema.Resource {
 			"project": {
 				Type:     schema.TypeString,
 				Required: true,
-				ForceNew: true,
 			},
 			"description": {
 				Type:     schema.TypeString,
@@ -86,6 +94,22 @@ func resourceRepository() *schema.Resource {
 				Optional: true,
 				ForceNew: true,
 			},
+			"default_branch": {
+				Type:             schema.TypeString,
+				Optional:         true,
+				Computed:         true,
+				DiffSuppressFunc: suppressBranchRefPrefix,
+			},
+			"archived": {
+				Type:     schema.TypeBool,
+				Optional: true,
+				Default:  false,
+			},
+			"deletion_protection": {
+				Type:     schema.TypeBool,
+				Optional: true,
+				Default:  false,
+			},
 			"enable_git_lfs": {
 				Type:     schema.TypeBool,
 				Optional: true,
@@ -115,10 +139,92 @@ func newRepositoryFromResource(d *schema.ResourceData) (Repo *Repository) {
 	return repo
 }
 
+// resourceRepositoryCustomizeDiff refuses to replace protected repositories or to archive them on
+// servers without archiving. It marks the attributes derived from the repository location as
+// unknown when it is renamed or moved, so dependent resources pick up the new values.
+func resourceRepositoryCustomizeDiff(d *schema.ResourceDiff, m interface{}) error {
+	if d.Get("archived").(bool) && (d.Id() == "" || d.HasChange("archived")) {
+		serverInfo, err := m.(*BitbucketServerProvider).serverInfo()
+		if err != nil {
+			return err
+		}
+		if err := serverInfo.Require(CapabilityRepositoryArchiving); err != nil {
+			return err
+		}
+	}
+
+	if d.Id() == "" {
+		return nil
+	}
+
+	project, _ := d.GetChange("project")
+	err := checkReplacementProtection(d, m, resourceRepository().Schema, "repository "+d.Id(), project.(string))
+	if err != nil {
+		return err
+	}
+
+	// archived repositories are read-only, they are only updated along with unarchiving them
+	wasArchived, _ := d.GetChange("archived")
+	if wasArchived.(bool) && d.Get("archived").(bool) && !requiresReplacement(d, resourceRepository().Schema) {
+		for _, key := range []string{"name", "project", "description", "forkable", "public", "default_branch", "enable_git_lfs"} {
+			if d.HasChange(key) {
+				return fmt.Errorf("repository %s is archived and cannot change %s, set archived to false to unarchive it first", d.Id(), key)
+			}
+		}
+	}
+
+	if d.HasChange("name") {
+		// Bitbucket derives the slug from the name
+		if err := d.SetNewComputed("slug"); err != nil {
+			return err
+		}
+	}
+
+	if d.HasChange("name") || d.HasChange("project") {
+		for _, key := range []string{"clone_ssh", "clone_https"} {
+			if err := d.SetNewComputed(key); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// parseRepositoryID splits a repository ID into the project key and the repository slug.
+func parseRepositoryID(id string) (string, string, error) {
+	idparts := strings.Split(id, "/")
+	if len(idparts) != 2 {
+		return "", "", fmt.Errorf("incorrect ID format, should match `project/slug`")
+	}
+	return idparts[0], idparts[1], nil
+}
+
 func resourceRepositoryUpdate(d *schema.ResourceData, m interface{}) error {
 	client := m.(*BitbucketServerProvider).BitbucketClient
+
+	// the ID holds the current location of the repository, the configuration the desired one
+	currentProject, currentSlug, err := parseRepositoryID(d.Id())
+	if err != nil {
+		return err
+	}
+
 	project := d.Get("project").(string)
 	repo := newRepositoryFromResource(d)
+	if d.HasChange("name") {
+		// the slug in state belongs to the old name, Bitbucket derives the new one
+		repo.Slug = ""
+	}
+	if project != currentProject {
+		repo.Project = &RepositoryForkProject{Key: project}
+	}
+	archive := d.HasChange("archived") && d.Get("archived").(bool)
+	if d.HasChange("archived") && !archive {
+		// only sent when toggled, so servers without archiving accept the other updates. Archiving is
+		// sent last, as archived repositories reject further changes.
+		unarchive := false
+		repo.Archived = &unarchive
+	}
 
 	bytedata, err := json.Marshal(repo)
 
@@ -126,25 +232,64 @@ func resourceRepositoryUpdate(d *schema.ResourceData, m interface{}) error {
 		return err
 	}
 
-	repoSlug := determineSlug(d)
-
-	_, err = client.Put(fmt.Sprintf("/rest/api/1.0/projects/%s/repos/%s",
-		project,
-		repoSlug,
+	resp, err := client.Put(fmt.Sprintf("/rest/api/1.0/projects/%s/repos/%s",
+		currentProject,
+		currentSlug,
 	), bytes.NewBuffer(bytedata))
 
 	if err != nil {
 		return err
 	}
 
+	updated, err := decodeRepository(resp)
+	if err != nil {
+		return err
+	}
+
+	repoSlug := currentSlug
+	if updated.Slug != "" {
+		repoSlug = updated.Slug
+	}
+	d.SetId(fmt.Sprintf("%s/%s", project, repoSlug))
+
 	err = handleRepositoryGitLFSChanges(client, project, repoSlug, d)
 	if err != nil {
 		return err
 	}
 
+	// applied while the repository is still writable, after unarchiving and before archiving it. New
+	// repositories get their default branch in create.
+	if !d.IsNewResource() && d.HasChange("default_branch") {
+		err = setRepositoryDefaultBranch(m.(*BitbucketServerProvider), project, repoSlug, d.Get("default_branch").(string))
+		if err != nil {
+			return err
+		}
+	}
+
+	if archive {
+		err = setRepositoryArchived(client, project, repoSlug, true)
+		if err != nil {
+			return err
+		}
+	}
+
 	return resourceRepositoryRead(d, m)
 }
 
+// setRepositoryArchived archives or unarchives the repository.
+func setRepositoryArchived(client *BitbucketClient, project string, repoSlug string, archived bool) error {
+	bytedata, err := json.Marshal(map[string]bool{"archived": archived})
+	if err != nil {
+		return err
+	}
+
+	_, err = client.Put(fmt.Sprintf("/rest/api/1.0/projects/%s/repos/%s",
+		project,
+		repoSlug,
+	), bytes.NewBuffer(bytedata))
+	return err
+}
+
 func resourceRepositoryCreate(d *schema.ResourceData, m interface{}) error {
 	client := m.(*BitbucketServerProvider).BitbucketClient
 
@@ -158,53 +303,67 @@ func resourceRepositoryCreate(d *schema.ResourceData, m interface{}) error {
 		return fmt.Errorf("both fork_repository_project and fork_repository_slug need to be specified when forking an existing repository")
 	}
 
+	var created *Repository
+	var err error
 	if forkProject != "" {
-		err := createNewRepositoryFromFork(client, d, project, repoSlug, forkProject, forkRepo)
-		if err != nil {
-			return err
-		}
+		created, err = createNewRepositoryFromFork(client, d, project, repoSlug, forkProject, forkRepo)
 	} else {
-		err := createNewRepository(client, d, project)
-		if err != nil {
-			return err
-		}
+		created, err = createNewRepository(client, d, project)
+	}
+	if err != nil {
+		return err
 	}
 
-	d.SetId(string(fmt.Sprintf("%s/%s", project, name)))
+	// the ID holds the slug assigned by Bitbucket, which differs from the name for names with spaces
+	if created.Slug != "" {
+		repoSlug = created.Slug
+	} else if d.Get("slug").(string) == "" {
+		repoSlug = name
+	}
+	d.SetId(fmt.Sprintf("%s/%s", project, repoSlug))
 
-	err := handleRepositoryGitLFSChanges(client, project, repoSlug, d)
+	err = handleRepositoryGitLFSChanges(client, project, repoSlug, d)
 	if err != nil {
 		return err
 	}
 
-	if forkProject != "" {
-		// after forking a repository, run the update loop to update any names/descriptions etc of the forked repo
+	// forks and new repositories start on the server default otherwise
+	if defaultBranch := d.Get("default_branch").(string); defaultBranch != "" {
+		err = setRepositoryDefaultBranch(m.(*BitbucketServerProvider), project, repoSlug, defaultBranch)
+		if err != nil {
+			return err
+		}
+	}
+
+	if forkProject != "" || d.Get("archived").(bool) {
+		// after forking a repository, run the update loop to update any names/descriptions etc of the forked repo,
+		// repositories are archived after creation as well
 		return resourceRepositoryUpdate(d, m)
 	} else {
 		return resourceRepositoryRead(d, m)
 	}
 }
 
-func createNewRepository(client *BitbucketClient, d *schema.ResourceData, project string) error {
+func createNewRepository(client *BitbucketClient, d *schema.ResourceData, project string) (*Repository, error) {
 	repo := newRepositoryFromResource(d)
 	bytedata, err := json.Marshal(repo)
 
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	_, err = client.Post(fmt.Sprintf("/rest/api/1.0/projects/%s/repos",
+	resp, err := client.Post(fmt.Sprintf("/rest/api/1.0/projects/%s/repos",
 		project,
 	), bytes.NewBuffer(bytedata))
 
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	return nil
+	return decodeRepository(resp)
 }
 
-func createNewRepositoryFromFork(client *BitbucketClient, d *schema.ResourceData, project string, repository string, forkProject string, forkRepository string) error {
+func createNewRepositoryFromFork(client *BitbucketClient, d *schema.ResourceData, project string, repository string, forkProject string, forkRepository string) (*Repository, error) {
 	requestBody := &RepositoryFork{
 		Name: repository,
 		Project: RepositoryForkProject{
@@ -214,15 +373,25 @@ func createNewRepositoryFromFork(client *BitbucketClient, d *schema.ResourceData
 
 	bytedata, err := json.Marshal(requestBody)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	_, err = client.Post(fmt.Sprintf("/rest/api/1.0/projects/%s/repos/%s", forkProject, forkRepository), bytes.NewBuffer(bytedata))
+	resp, err := client.Post(fmt.Sprintf("/rest/api/1.0/projects/%s/repos/%s", forkProject, forkRepository), bytes.NewBuffer(bytedata))
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	return nil
+	return decodeRepository(resp)
+}
+
+// decodeRepository decodes the repository returned by a create or update request.
+func decodeRepository(resp *http.Response) (*Repository, error) {
+	var repo Repository
+	err := json.NewDecoder(resp.Body).Decode(&repo)
+	if err != nil {
+		return nil, err
+	}
+	return &repo, nil
 }
 
 func handleRepositoryGitLFSChanges(client *BitbucketClient, project string, repoSlug string, d *schema.ResourceData) error {
@@ -253,15 +422,13 @@ func handleRepositoryGitLFSChanges(client *BitbucketClient, project string, repo
 }
 
 func resourceRepositoryRead(d *schema.ResourceData, m interface{}) error {
-	id := d.Id()
-	if id != "" {
-		idparts := strings.Split(id, "/")
-		if len(idparts) == 2 {
-			_ = d.Set("project", idparts[0])
-			_ = d.Set("slug", idparts[1])
-		} else {
-			return fmt.Errorf("incorrect ID format, should match `project/slug`")
+	if d.Id() != "" {
+		project, slug, err := parseRepositoryID(d.Id())
+		if err != nil {
+			return err
 		}
+		_ = d.Set("project", project)
+		_ = d.Set("slug", slug)
 	}
 
 	repoSlug := determineSlug(d)
@@ -274,7 +441,7 @@ func resourceRepositoryRead(d *schema.ResourceData, m interface{}) error {
 	))
 
 	if err != nil {
-		return err
+		return removeIfNotFound(d, err)
 	}
 
 	if repo_req.StatusCode == 200 {
@@ -298,6 +465,7 @@ func resourceRepositoryRead(d *schema.ResourceData, m interface{}) error {
 		_ = d.Set("description", repo.Description)
 		_ = d.Set("forkable", repo.Forkable)
 		_ = d.Set("public", repo.Public)
+		_ = d.Set("archived", repo.Archived != nil && *repo.Archived)
 
 		for _, clone_url := range repo.Links.Clone {
 			if clone_url.Name == "http" {
@@ -307,6 +475,15 @@ func resourceRepositoryRead(d *schema.ResourceData, m interface{}) error {
 			}
 		}
 
+		defaultBranch, err := readRepositoryDefaultBranch(m.(*BitbucketServerProvider), project, repoSlug)
+		if err != nil {
+			return err
+		}
+		// repositories without commits have no default branch to compare against
+		if defaultBranch != "" {
+			_ = d.Set("default_branch", defaultBranch)
+		}
+
 		gifLFS, err := client.Get(fmt.Sprintf("/rest/git-lfs/admin/projects/%s/repos/%s/enabled",
 			project,
 			repoSlug,
@@ -319,17 +496,9 @@ func resourceRepositoryRead(d *schema.ResourceData, m interface{}) error {
 
 func resourceRepositoryExists(d *schema.ResourceData, m interface{}) (bool, error) {
 
-	var project = ""
-	var repoSlug = ""
-	id := d.Id()
-	if id != "" {
-		idparts := strings.Split(id, "/")
-		if len(idparts) == 2 {
-			project = idparts[0]
-			repoSlug = idparts[1]
-		} else {
-			return false, fmt.Errorf("incorrect ID format, should match `project/slug`")
-		}
+	project, repoSlug, err := parseRepositoryID(d.Id())
+	if err != nil {
+		return false, err
 	}
 
 	client := m.(*BitbucketServerProvider).BitbucketClient
@@ -338,6 +507,9 @@ func resourceRepositoryExists(d *schema.ResourceData, m interface{}) (bool, erro
 		repoSlug,
 	))
 
+	if IsNotFound(err) {
+		return false, nil
+	}
 	if err != nil {
 		return false, fmt.Errorf("failed to get repository %s/%s from bitbucket: %+v", project, repoSlug, err)
 	}
@@ -352,8 +524,14 @@ func resourceRepositoryExists(d *schema.ResourceData, m interface{}) (bool, erro
 func resourceRepositoryDelete(d *schema.ResourceData, m interface{}) error {
 	repoSlug := determineSlug(d)
 	project := d.Get("project").(string)
+
+	err := checkDeletionProtection(m, "repository "+d.Id(), project, d.Get("deletion_protection").(bool))
+	if err != nil {
+		return err
+	}
+
 	client := m.(*BitbucketServerProvider).BitbucketClient
-	_, err := client.Delete(fmt.Sprintf("/rest/api/1.0/projects/%s/repos/%s",
+	_, err = client.Delete(fmt.Sprintf("/rest/api/1.0/projects/%s/repos/%s",
 		project,
 		repoSlug,
 	))
@@ -361,6 +539,71 @@ func resourceRepositoryDelete(d *schema.ResourceData, m interface{}) error {
 	return err
 }
 
+// repositoryDefaultBranchEndpoint returns the default branch endpoint of the repository, the
+// deprecated branches/default endpoint on servers without the default-branch endpoint.
+func repositoryDefaultBranchEndpoint(provider *BitbucketServerProvider, project string, repoSlug string) (string, error) {
+	serverInfo, err := provider.serverInfo()
+	if err != nil {
+		return "", err
+	}
+
+	if serverInfo.Supports(CapabilityDefaultBranchEndpoint) {
+		return fmt.Sprintf("/rest/api/1.0/projects/%s/repos/%s/default-branch", project, repoSlug), nil
+	}
+	return fmt.Sprintf("/rest/api/1.0/projects/%s/repos/%s/branches/default", project, repoSlug), nil
+}
+
+// readRepositoryDefaultBranch returns the name of the default branch of the repository, or an
+// empty string if the repository has none yet.
+func readRepositoryDefaultBranch(provider *BitbucketServerProvider, project string, repoSlug string) (string, error) {
+	endpoint, err := repositoryDefaultBranchEndpoint(provider, project, repoSlug)
+	if err != nil {
+		return "", err
+	}
+
+	resp, err := provider.BitbucketClient.Get(endpoint)
+	if IsNotFound(err) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	if resp.StatusCode == http.StatusNoContent {
+		return "", nil
+	}
+
+	var branch RepositoryBranch
+	err = json.NewDecoder(resp.Body).Decode(&branch)
+	if err != nil {
+		return "", err
+	}
+
+	if branch.DisplayID != "" {
+		return branch.DisplayID, nil
+	}
+	return strings.TrimPrefix(branch.ID, "refs/heads/"), nil
+}
+
+func setRepositoryDefaultBranch(provider *BitbucketServerProvider, project string, repoSlug string, branch string) error {
+	endpoint, err := repositoryDefaultBranchEndpoint(provider, project, repoSlug)
+	if err != nil {
+		return err
+	}
+
+	bytedata, err := json.Marshal(RepositoryBranch{ID: "refs/heads/" + strings.TrimPrefix(branch, "refs/heads/")})
+	if err != nil {
+		return err
+	}
+
+	_, err = provider.BitbucketClient.Put(endpoint, bytes.NewBuffer(bytedata))
+	return err
+}
+
+// suppressBranchRefPrefix treats a branch name and its full refs/heads/ reference as equal.
+func suppressBranchRefPrefix(k, old, new string, d *schema.ResourceData) bool {
+	return strings.TrimPrefix(old, "refs/heads/") == strings.TrimPrefix(new, "refs/heads/")
+}
+
 func determineSlug(d *schema.ResourceData) string {
 	var repoSlug string
 	repoSlug = d.Get("slug").(string)
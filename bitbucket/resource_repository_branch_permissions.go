@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
+	"log"
 	"strings"
 
 	"github.com/hashicorp/terraform/helper/schema"
@@ -57,13 +58,6 @@ type BranchPermissionResponse struct {
 	} `json:"accessKeys"`
 }
 
-type AllRepositoryBranchPermissionsResponse struct {
-	Size       int                        `json:"size"`
-	Limit      int                        `json:"limit"`
-	IsLastPage bool                       `json:"isLastPage"`
-	Values     []BranchPermissionResponse `json:"values"`
-}
-
 func resourceBranchPermissions() *schema.Resource {
 	return &schema.Resource{
 		Create: resourceBranchPermissionsCreate,
@@ -223,7 +217,7 @@ func resourceBranchPermissionsRead(d *schema.ResourceData, m interface{}) error
 	}
 
 	if err != nil {
-		return err
+		return removeIfNotFound(d, err)
 	}
 
 	return nil
@@ -284,26 +278,18 @@ func getBranchPermissionFromList(d *schema.ResourceData, m interface{}) error {
 
 	client := m.(*BitbucketServerProvider).BitbucketClient
 
-	resp, err := client.Get(fmt.Sprintf("/rest/branch-permissions/2.0/projects/%s/repos/%s/restrictions",
+	// every page is read before a missing branch permission is treated as deleted
+	var branchPermissions []BranchPermissionResponse
+	err := client.GetAllPages(fmt.Sprintf("/rest/branch-permissions/2.0/projects/%s/repos/%s/restrictions",
 		project,
 		repository,
-	))
+	), nil, &branchPermissions)
 
 	if err != nil {
 		return err
 	}
 
-	var allRepositoryBranchPermissionsResponse AllRepositoryBranchPermissionsResponse
-
-	decoder := json.NewDecoder(resp.Body)
-
-	err = decoder.Decode(&allRepositoryBranchPermissionsResponse)
-
-	if err != nil {
-		return err
-	}
-
-	for _, item := range allRepositoryBranchPermissionsResponse.Values {
+	for _, item := range branchPermissions {
 		if strings.ToLower(strings.Replace(item.Type, "_", "-", -1)) == restrictionType {
 			_ = d.Set("permission_id", item.Id)
 			_ = d.Set("type", item.Type)
@@ -327,7 +313,9 @@ func getBranchPermissionFromList(d *schema.ResourceData, m interface{}) error {
 		}
 	}
 
-	return fmt.Errorf("incorrect ID format, should match `project|repository|ref_pattern`")
+	log.Printf("[WARN] Branch permission %s not found, removing from state", d.Id())
+	d.SetId("")
+	return nil
 }
 
 func resourceBranchPermissionsDelete(d *schema.ResourceData, m interface{}) error {
@@ -337,5 +325,5 @@ func resourceBranchPermissionsDelete(d *schema.ResourceData, m interface{}) erro
 		d.Get("repository").(string),
 		d.Get("permission_id").(int)))
 
-	return err
+	return ignoreNotFound(err)
 }
@@ -103,7 +103,7 @@ func resourceRepositoryHookRead(d *schema.ResourceData, m interface{}) error {
 	))
 
 	if err != nil {
-		return err
+		return removeIfNotFound(d, err)
 	}
 
 	var settings map[string]interface{}
@@ -125,5 +125,5 @@ func resourceRepositoryHookDelete(d *schema.ResourceData, m interface{}) error {
 		d.Get("repository").(string),
 		d.Get("hook").(string)))
 
-	return err
+	return ignoreNotFound(err)
 }
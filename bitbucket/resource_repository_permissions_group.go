@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"github.com/hashicorp/terraform/helper/schema"
 	"github.com/hashicorp/terraform/helper/validation"
+	"log"
 	"net/url"
 	"strings"
 )
@@ -51,6 +52,7 @@ func resourceRepositoryPermissionsGroupUpdate(d *schema.ResourceData, m interfac
 		url.QueryEscape(d.Get("permission").(string)),
 		url.QueryEscape(d.Get("group").(string)),
 	), nil)
+	m.(*BitbucketServerProvider).readCache.invalidate(repositoryPermissionsGroupsScope(d.Get("project").(string), d.Get("repository").(string)))
 
 	if err != nil {
 		return err
@@ -83,19 +85,21 @@ func resourceRepositoryPermissionsGroupRead(d *schema.ResourceData, m interface{
 	}
 
 	group := d.Get("group").(string)
-	groups, err := readRepositoryPermissionsGroups(m, d.Get("project").(string), d.Get("repository").(string), group)
+	groups, err := readCachedRepositoryPermissionsGroups(m, d.Get("project").(string), d.Get("repository").(string))
 	if err != nil {
-		return err
+		return removeIfNotFound(d, err)
 	}
 
-	// API only filters but we need to find an exact match
+	// the cached list holds every group of the repository, find the exact match
 	for _, g := range groups {
 		if g.Name == group {
 			_ = d.Set("permission", g.Permission)
-			break
+			return nil
 		}
 	}
 
+	log.Printf("[WARN] Repository permission %s not found, removing from state", d.Id())
+	d.SetId("")
 	return nil
 }
 
@@ -106,6 +110,7 @@ func resourceRepositoryPermissionsGroupDelete(d *schema.ResourceData, m interfac
 		url.QueryEscape(d.Get("repository").(string)),
 		url.QueryEscape(d.Get("group").(string)),
 	))
+	m.(*BitbucketServerProvider).readCache.invalidate(repositoryPermissionsGroupsScope(d.Get("project").(string), d.Get("repository").(string)))
 
-	return err
+	return ignoreNotFound(err)
 }
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"github.com/hashicorp/terraform/helper/schema"
 	"github.com/hashicorp/terraform/helper/validation"
+	"log"
 	"net/url"
 	"strings"
 )
@@ -51,6 +52,7 @@ func resourceRepositoryPermissionsUserUpdate(d *schema.ResourceData, m interface
 		url.QueryEscape(d.Get("permission").(string)),
 		url.QueryEscape(d.Get("user").(string)),
 	), nil)
+	m.(*BitbucketServerProvider).readCache.invalidate(repositoryPermissionsUsersScope(d.Get("project").(string), d.Get("repository").(string)))
 
 	if err != nil {
 		return err
@@ -83,19 +85,21 @@ func resourceRepositoryPermissionsUserRead(d *schema.ResourceData, m interface{}
 	}
 
 	user := d.Get("user").(string)
-	users, err := readRepositoryPermissionsUsers(m, d.Get("project").(string), d.Get("repository").(string), user)
+	users, err := readCachedRepositoryPermissionsUsers(m, d.Get("project").(string), d.Get("repository").(string))
 	if err != nil {
-		return err
+		return removeIfNotFound(d, err)
 	}
 
-	// API only filters but we need to find an exact match
+	// the cached list holds every user of the repository, find the exact match
 	for _, g := range users {
 		if g.Name == user {
 			_ = d.Set("permission", g.Permission)
-			break
+			return nil
 		}
 	}
 
+	log.Printf("[WARN] Repository permission %s not found, removing from state", d.Id())
+	d.SetId("")
 	return nil
 }
 
@@ -106,6 +110,7 @@ func resourceRepositoryPermissionsUserDelete(d *schema.ResourceData, m interface
 		url.QueryEscape(d.Get("repository").(string)),
 		url.QueryEscape(d.Get("user").(string)),
 	))
+	m.(*BitbucketServerProvider).readCache.invalidate(repositoryPermissionsUsersScope(d.Get("project").(string), d.Get("repository").(string)))
 
-	return err
+	return ignoreNotFound(err)
 }
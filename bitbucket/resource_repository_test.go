@@ -1,13 +1,18 @@
 package bitbucket
 
 import (
+	"encoding/json"
 	"fmt"
 	"math/rand"
+	"net/http"
+	"net/http/httptest"
 	"strings"
 	"testing"
 	"time"
 
+	"github.com/hashicorp/terraform/config"
 	"github.com/hashicorp/terraform/helper/resource"
+	"github.com/hashicorp/terraform/helper/schema"
 	"github.com/hashicorp/terraform/terraform"
 )
 
@@ -195,6 +200,64 @@ func TestAccBitbucketRepository_gitlfs(t *testing.T) {
 	})
 }
 
+func TestAccBitbucketRepository_renameAndMove(t *testing.T) {
+	var repo Repository
+
+	key := fmt.Sprintf("%v", rand.New(rand.NewSource(time.Now().UnixNano())).Int())
+	config := fmt.Sprintf(`
+		resource "bitbucketserver_project" "test" {
+			key = "TEST%v"
+			name = "Test%v"
+		}
+
+		resource "bitbucketserver_project" "other" {
+			key = "OTHER%v"
+			name = "Other%v"
+		}
+
+		resource "bitbucketserver_repository" "test_repo" {
+			project = bitbucketserver_project.test.key
+			name = "test-repo-for-repository-test"
+		}
+
+		resource "bitbucketserver_repository_permissions_group" "test" {
+			project = bitbucketserver_repository.test_repo.project
+			repository = bitbucketserver_repository.test_repo.slug
+			group = "stash-users"
+			permission = "REPO_READ"
+		}
+	`, key, key, key, key)
+
+	configModified := strings.ReplaceAll(config, `project = bitbucketserver_project.test.key
+			name = "test-repo-for-repository-test"`, `project = bitbucketserver_project.other.key
+			name = "Renamed Repo"`)
+
+	resource.Test(t, resource.TestCase{
+		PreCheck:     func() { testAccPreCheck(t) },
+		Providers:    testAccProviders,
+		CheckDestroy: testAccCheckBitbucketRepositoryDestroy,
+		Steps: []resource.TestStep{
+			{
+				Config: config,
+				Check: resource.ComposeTestCheckFunc(
+					testAccCheckBitbucketRepositoryExists("bitbucketserver_repository.test_repo", &repo),
+					resource.TestCheckResourceAttr("bitbucketserver_repository.test_repo", "id", "TEST"+key+"/test-repo-for-repository-test"),
+				),
+			},
+			{
+				Config: configModified,
+				Check: resource.ComposeTestCheckFunc(
+					testAccCheckBitbucketRepositoryExists("bitbucketserver_repository.test_repo", &repo),
+					resource.TestCheckResourceAttr("bitbucketserver_repository.test_repo", "id", "OTHER"+key+"/renamed-repo"),
+					resource.TestCheckResourceAttr("bitbucketserver_repository.test_repo", "slug", "renamed-repo"),
+					resource.TestCheckResourceAttr("bitbucketserver_repository.test_repo", "project", "OTHER"+key),
+					resource.TestCheckResourceAttr("bitbucketserver_repository_permissions_group.test", "repository", "renamed-repo"),
+				),
+			},
+		},
+	})
+}
+
 func testAccCheckBitbucketRepositoryDestroy(s *terraform.State) error {
 	client := testAccProvider.Meta().(*BitbucketServerProvider).BitbucketClient
 	rs, ok := s.RootModule().Resources["bitbucketserver_repository.test_repo"]
@@ -223,3 +286,299 @@ func testAccCheckBitbucketRepositoryExists(n string, repository *Repository) res
 		return nil
 	}
 }
+
+func TestResourceRepositoryUpdate_RenameAndMove(t *testing.T) {
+	var update Repository
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == "PUT" && r.URL.Path == "/rest/api/1.0/projects/OLD/repos/old-repo":
+			_ = json.NewDecoder(r.Body).Decode(&update)
+			fmt.Fprint(w, `{"name": "New Repo", "slug": "new-repo", "project": {"key": "NEW"}}`)
+		case r.Method == "GET" && r.URL.Path == "/rest/api/1.0/projects/NEW/repos/new-repo":
+			fmt.Fprint(w, `{"name": "New Repo", "slug": "new-repo", "forkable": true, "project": {"key": "NEW"}}`)
+		case r.Method == "GET" && strings.HasSuffix(r.URL.Path, "/default-branch"):
+			fmt.Fprint(w, `{"id": "refs/heads/main", "displayId": "main"}`)
+		case r.Method == "GET" && strings.HasPrefix(r.URL.Path, "/rest/git-lfs/"):
+			w.WriteHeader(http.StatusNotFound)
+		default:
+			t.Errorf("unexpected request %s %s", r.Method, r.URL)
+		}
+	}))
+	defer server.Close()
+
+	provider := newTestProvider(server)
+	provider.ServerInfo = &ServerInfo{Version: ServerVersion{Major: 8, Minor: 9}}
+
+	d := schema.TestResourceDataRaw(t, resourceRepository().Schema, map[string]interface{}{
+		"project": "NEW",
+		"name":    "New Repo",
+	})
+	d.SetId("OLD/old-repo")
+
+	if err := resourceRepositoryUpdate(d, provider); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	if update.Name != "New Repo" || update.Slug != "" || update.Project == nil || update.Project.Key != "NEW" {
+		t.Fatalf("unexpected update %+v", update)
+	}
+	if d.Id() != "NEW/new-repo" {
+		t.Fatalf("expected ID NEW/new-repo, got %s", d.Id())
+	}
+	if d.Get("slug").(string) != "new-repo" || d.Get("project").(string) != "NEW" {
+		t.Fatalf("unexpected slug %s and project %s", d.Get("slug"), d.Get("project"))
+	}
+}
+
+func TestResourceRepositoryUpdate_Archived(t *testing.T) {
+	// archived repositories reject changes, so archiving has to come after every other change
+	var requests []string
+	var updates []map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "GET" {
+			requests = append(requests, r.Method+" "+r.URL.Path)
+		}
+		switch {
+		case r.Method == "PUT" && r.URL.Path == "/rest/api/1.0/projects/TEST/repos/repo":
+			var update map[string]interface{}
+			_ = json.NewDecoder(r.Body).Decode(&update)
+			updates = append(updates, update)
+			fmt.Fprint(w, `{"name": "repo", "slug": "repo"}`)
+		case r.Method == "PUT" && r.URL.Path == "/rest/git-lfs/admin/projects/TEST/repos/repo/enabled":
+		case r.Method == "PUT" && r.URL.Path == "/rest/api/1.0/projects/TEST/repos/repo/default-branch":
+		case r.Method == "GET" && r.URL.Path == "/rest/api/1.0/projects/TEST/repos/repo":
+			fmt.Fprint(w, `{"name": "repo", "slug": "repo", "forkable": false, "archived": true}`)
+		case r.Method == "GET" && strings.HasSuffix(r.URL.Path, "/default-branch"):
+			fmt.Fprint(w, `{"id": "refs/heads/develop", "displayId": "develop"}`)
+		case r.Method == "GET" && strings.HasPrefix(r.URL.Path, "/rest/git-lfs/"):
+			fmt.Fprint(w, `{"enabled": true}`)
+		default:
+			t.Errorf("unexpected request %s %s", r.Method, r.URL)
+		}
+	}))
+	defer server.Close()
+
+	provider := newTestProvider(server)
+	provider.ServerInfo = &ServerInfo{Version: ServerVersion{Major: 8, Minor: 9}}
+
+	d := schema.TestResourceDataRaw(t, resourceRepository().Schema, map[string]interface{}{
+		"project":        "TEST",
+		"name":           "repo",
+		"forkable":       false,
+		"enable_git_lfs": true,
+		"default_branch": "develop",
+		"archived":       true,
+	})
+	d.SetId("TEST/repo")
+
+	if err := resourceRepositoryUpdate(d, provider); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	expected := []string{
+		"PUT /rest/api/1.0/projects/TEST/repos/repo",
+		"PUT /rest/git-lfs/admin/projects/TEST/repos/repo/enabled",
+		"PUT /rest/api/1.0/projects/TEST/repos/repo/default-branch",
+		"PUT /rest/api/1.0/projects/TEST/repos/repo",
+	}
+	if strings.Join(requests, "\n") != strings.Join(expected, "\n") {
+		t.Fatalf("unexpected requests %v", requests)
+	}
+
+	if _, ok := updates[0]["archived"]; ok || updates[0]["forkable"] != false {
+		t.Fatalf("unexpected update %v", updates[0])
+	}
+	if len(updates[1]) != 1 || updates[1]["archived"] != true {
+		t.Fatalf("unexpected archive update %v", updates[1])
+	}
+	if !d.Get("archived").(bool) {
+		t.Fatal("expected the repository to be read back as archived")
+	}
+}
+
+func TestResourceRepositoryUpdate_UnarchivedDefaultBranch(t *testing.T) {
+	var requests []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "GET" {
+			requests = append(requests, r.Method+" "+r.URL.Path)
+		}
+		switch {
+		case r.Method == "PUT" && r.URL.Path == "/rest/api/1.0/projects/TEST/repos/repo":
+			fmt.Fprint(w, `{"name": "repo", "slug": "repo"}`)
+		case r.Method == "PUT" && r.URL.Path == "/rest/api/1.0/projects/TEST/repos/repo/default-branch":
+		case r.Method == "GET" && r.URL.Path == "/rest/api/1.0/projects/TEST/repos/repo":
+			fmt.Fprint(w, `{"name": "repo", "slug": "repo", "forkable": true, "archived": false}`)
+		case r.Method == "GET" && strings.HasSuffix(r.URL.Path, "/default-branch"):
+			fmt.Fprint(w, `{"id": "refs/heads/develop", "displayId": "develop"}`)
+		case r.Method == "GET" && strings.HasPrefix(r.URL.Path, "/rest/git-lfs/"):
+			w.WriteHeader(http.StatusNotFound)
+		default:
+			t.Errorf("unexpected request %s %s", r.Method, r.URL)
+		}
+	}))
+	defer server.Close()
+
+	provider := newTestProvider(server)
+	provider.ServerInfo = &ServerInfo{Version: ServerVersion{Major: 8, Minor: 9}}
+
+	state := &terraform.InstanceState{
+		ID: "TEST/repo",
+		Attributes: map[string]string{
+			"id":             "TEST/repo",
+			"project":        "TEST",
+			"name":           "repo",
+			"slug":           "repo",
+			"forkable":       "true",
+			"default_branch": "main",
+			"archived":       "true",
+		},
+	}
+	rawConfig, err := config.NewRawConfig(map[string]interface{}{
+		"project":        "TEST",
+		"name":           "repo",
+		"default_branch": "develop",
+		"archived":       false,
+	})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	diff, err := resourceRepository().Diff(state, terraform.NewResourceConfig(rawConfig), provider)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	d, err := schema.InternalMap(resourceRepository().Schema).Data(state, diff)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	if err := resourceRepositoryUpdate(d, provider); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	expected := []string{
+		"PUT /rest/api/1.0/projects/TEST/repos/repo",
+		"PUT /rest/api/1.0/projects/TEST/repos/repo/default-branch",
+	}
+	if strings.Join(requests, "\n") != strings.Join(expected, "\n") {
+		t.Fatalf("unexpected requests %v", requests)
+	}
+}
+
+func TestResourceRepositoryDiff_ArchivedReadOnly(t *testing.T) {
+	state := &terraform.InstanceState{
+		ID: "TEST/repo",
+		Attributes: map[string]string{
+			"id":             "TEST/repo",
+			"project":        "TEST",
+			"name":           "repo",
+			"slug":           "repo",
+			"description":    "old",
+			"forkable":       "true",
+			"public":         "false",
+			"enable_git_lfs": "false",
+			"default_branch": "main",
+			"archived":       "true",
+		},
+	}
+	provider := &BitbucketServerProvider{ServerInfo: &ServerInfo{Version: ServerVersion{Major: 8, Minor: 9}}}
+
+	diff := func(raw map[string]interface{}) error {
+		rawConfig, err := config.NewRawConfig(raw)
+		if err != nil {
+			t.Fatalf("err: %s", err)
+		}
+		_, err = resourceRepository().Diff(state, terraform.NewResourceConfig(rawConfig), provider)
+		return err
+	}
+
+	err := diff(map[string]interface{}{"project": "TEST", "name": "repo", "description": "new", "archived": true})
+	if err == nil || !strings.Contains(err.Error(), "unarchive it first") {
+		t.Fatalf("expected an archived error, got %v", err)
+	}
+
+	err = diff(map[string]interface{}{"project": "TEST", "name": "repo", "description": "old", "default_branch": "develop", "archived": true})
+	if err == nil || !strings.Contains(err.Error(), "cannot change default_branch") {
+		t.Fatalf("expected an archived error, got %v", err)
+	}
+
+	if err := diff(map[string]interface{}{"project": "TEST", "name": "repo", "description": "new", "archived": false}); err != nil {
+		t.Fatalf("unexpected error when unarchiving along with the change: %s", err)
+	}
+
+	if err := diff(map[string]interface{}{"project": "TEST", "name": "repo", "description": "old", "archived": true}); err != nil {
+		t.Fatalf("unexpected error without changes: %s", err)
+	}
+}
+
+func TestResourceRepositoryDiff_ArchivedRequiresServerSupport(t *testing.T) {
+	rawConfig, err := config.NewRawConfig(map[string]interface{}{
+		"project":  "TEST",
+		"name":     "repo",
+		"archived": true,
+	})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	provider := &BitbucketServerProvider{ServerInfo: &ServerInfo{Version: ServerVersion{Major: 7, Minor: 21}}}
+	_, err = resourceRepository().Diff(nil, terraform.NewResourceConfig(rawConfig), provider)
+	if err == nil || !strings.Contains(err.Error(), "repository archiving requires Bitbucket >= 8.0.0") {
+		t.Fatalf("expected a server version error, got %v", err)
+	}
+
+	provider.ServerInfo.Version = ServerVersion{Major: 8, Minor: 9}
+	if _, err = resourceRepository().Diff(nil, terraform.NewResourceConfig(rawConfig), provider); err != nil {
+		t.Fatalf("unexpected error on Bitbucket 8: %s", err)
+	}
+}
+
+func TestResourceRepositoryCreate_DefaultBranch(t *testing.T) {
+	for _, version := range []ServerVersion{{Major: 8, Minor: 9}, {Major: 7, Minor: 4}} {
+		t.Run(version.String(), func(t *testing.T) {
+			endpoint := "/rest/api/1.0/projects/TEST/repos/repo/default-branch"
+			if !version.AtLeast(ServerVersion{Major: 7, Minor: 5}) {
+				endpoint = "/rest/api/1.0/projects/TEST/repos/repo/branches/default"
+			}
+
+			var branch RepositoryBranch
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				switch {
+				case r.Method == "POST" && r.URL.Path == "/rest/api/1.0/projects/TEST/repos":
+					fmt.Fprint(w, `{"name": "repo", "slug": "repo"}`)
+				case r.Method == "PUT" && r.URL.Path == endpoint:
+					_ = json.NewDecoder(r.Body).Decode(&branch)
+				case r.Method == "GET" && r.URL.Path == "/rest/api/1.0/projects/TEST/repos/repo":
+					fmt.Fprint(w, `{"name": "repo", "slug": "repo", "forkable": true}`)
+				case r.Method == "GET" && r.URL.Path == endpoint:
+					// the server reports a different branch, the read must pick up the drift
+					fmt.Fprint(w, `{"id": "refs/heads/master", "displayId": "master"}`)
+				case r.Method == "GET" && strings.HasPrefix(r.URL.Path, "/rest/git-lfs/"):
+					w.WriteHeader(http.StatusNotFound)
+				default:
+					t.Errorf("unexpected request %s %s", r.Method, r.URL)
+				}
+			}))
+			defer server.Close()
+
+			provider := newTestProvider(server)
+			provider.ServerInfo = &ServerInfo{Version: version}
+
+			d := schema.TestResourceDataRaw(t, resourceRepository().Schema, map[string]interface{}{
+				"project":        "TEST",
+				"name":           "repo",
+				"default_branch": "main",
+			})
+
+			if err := resourceRepositoryCreate(d, provider); err != nil {
+				t.Fatalf("err: %s", err)
+			}
+
+			if branch.ID != "refs/heads/main" {
+				t.Fatalf("expected the default branch to be set to refs/heads/main, got %q", branch.ID)
+			}
+			if d.Get("default_branch").(string) != "master" {
+				t.Fatalf("expected the default branch read back as master, got %q", d.Get("default_branch"))
+			}
+		})
+	}
+}
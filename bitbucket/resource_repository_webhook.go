@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"github.com/hashicorp/terraform/helper/schema"
 	"io/ioutil"
+	"log"
 	"strings"
 )
 
@@ -24,14 +25,6 @@ type Webhook struct {
 	Configuration WebhookConfiguration `json:"configuration"`
 }
 
-type WebhookListResponse struct {
-	Size       int       `json:"size,omitempty"`
-	Limit      int       `json:"limit,omitempty"`
-	Start      int       `json:"start,omitempty"`
-	IsLastPage bool      `json:"isLastPage,omitempty"`
-	Values     []Webhook `json:"values"`
-}
-
 func resourceRepositoryWebhook() *schema.Resource {
 	return &schema.Resource{
 		Create: resourceRepositoryWebhookCreate,
@@ -177,7 +170,7 @@ func resourceRepositoryWebhookRead(d *schema.ResourceData, m interface{}) error
 	}
 
 	if err != nil {
-		return err
+		return removeIfNotFound(d, err)
 	}
 
 	return nil
@@ -190,7 +183,7 @@ func resourceRepositoryWebhookDelete(d *schema.ResourceData, m interface{}) erro
 		d.Get("repository").(string),
 		d.Get("webhook_id").(int)))
 
-	return err
+	return ignoreNotFound(err)
 }
 
 func newWebhookFromResource(d *schema.ResourceData) (Hook *Webhook) {
@@ -252,26 +245,18 @@ func getRepositoryWebhookFromList(d *schema.ResourceData, m interface{}) error {
 
 	client := m.(*BitbucketServerProvider).BitbucketClient
 
-	resp, err := client.Get(fmt.Sprintf("/rest/api/1.0/projects/%s/repos/%s/webhooks",
+	// every page is read before a missing webhook is treated as deleted
+	var webhooks []Webhook
+	err := client.GetAllPages(fmt.Sprintf("/rest/api/1.0/projects/%s/repos/%s/webhooks",
 		project,
 		repository,
-	))
+	), nil, &webhooks)
 
 	if err != nil {
 		return err
 	}
 
-	var webhookListResponse WebhookListResponse
-
-	decoder := json.NewDecoder(resp.Body)
-
-	err = decoder.Decode(&webhookListResponse)
-
-	if err != nil {
-		return err
-	}
-
-	for _, webhook := range webhookListResponse.Values {
+	for _, webhook := range webhooks {
 		if webhook.Name == name {
 			_ = d.Set("webhook_id", webhook.ID)
 			_ = d.Set("webhook_url", webhook.URL)
@@ -282,5 +267,7 @@ func getRepositoryWebhookFromList(d *schema.ResourceData, m interface{}) error {
 		}
 	}
 
-	return fmt.Errorf("incorrect ID format, should match `project/repository/name`")
+	log.Printf("[WARN] Webhook %s not found, removing from state", d.Id())
+	d.SetId("")
+	return nil
 }
@@ -3,10 +3,13 @@ package bitbucket
 import (
 	"fmt"
 	"math/rand"
+	"net/http"
+	"net/http/httptest"
 	"testing"
 	"time"
 
 	"github.com/hashicorp/terraform/helper/resource"
+	"github.com/hashicorp/terraform/helper/schema"
 )
 
 func TestAccBitbucketResourceRepositoryWebhook_simple(t *testing.T) {
@@ -113,3 +116,32 @@ func TestAccBitbucketResourceRepositoryWebhook_complete(t *testing.T) {
 		},
 	})
 }
+
+func TestGetRepositoryWebhookFromList_Paged(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("start") == "" {
+			fmt.Fprint(w, `{"values": [{"id": 1, "name": "first"}], "isLastPage": false, "nextPageStart": 1}`)
+			return
+		}
+		fmt.Fprint(w, `{"values": [{"id": 2, "name": "second", "url": "https://example.com"}], "isLastPage": true}`)
+	}))
+	defer server.Close()
+
+	d := schema.TestResourceDataRaw(t, resourceRepositoryWebhook().Schema, map[string]interface{}{
+		"project":    "TEST",
+		"repository": "repo",
+		"name":       "second",
+	})
+	d.SetId("TEST/repo/second")
+
+	if err := getRepositoryWebhookFromList(d, newTestProvider(server)); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	if d.Id() == "" {
+		t.Fatal("expected the webhook on the second page to be found")
+	}
+	if id := d.Get("webhook_id").(int); id != 2 {
+		t.Fatalf("expected webhook_id 2, got %d", id)
+	}
+}
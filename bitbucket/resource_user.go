@@ -2,12 +2,12 @@ package bitbucket
 
 import (
 	"bytes"
+	"crypto/rand"
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
-	"math/rand"
+	"math/big"
 	"net/url"
-	"time"
 
 	"github.com/hashicorp/terraform/helper/schema"
 	"github.com/hashicorp/terraform/helper/validation"
@@ -18,6 +18,13 @@ type User struct {
 	EmailAddress string `json:"emailAddress,omitempty"`
 	DisplayName  string `json:"displayName,omitempty"`
 	UserId       int    `json:"id,omitempty"`
+	Slug         string `json:"slug,omitempty"`
+}
+
+type UserCredentials struct {
+	Name            string `json:"name"`
+	Password        string `json:"password"`
+	PasswordConfirm string `json:"passwordConfirm"`
 }
 
 type UserUpdate struct {
@@ -28,11 +35,12 @@ type UserUpdate struct {
 
 func resourceUser() *schema.Resource {
 	return &schema.Resource{
-		Create: resourceUserCreate,
-		Update: resourceUserUpdate,
-		Read:   resourceUserRead,
-		Exists: resourceUserExists,
-		Delete: resourceUserDelete,
+		Create:        resourceUserCreate,
+		Update:        resourceUserUpdate,
+		Read:          resourceUserRead,
+		Exists:        resourceUserExists,
+		Delete:        resourceUserDelete,
+		CustomizeDiff: resourceUserCustomizeDiff,
 		Importer: &schema.ResourceImporter{
 			State: schema.ImportStatePassthrough,
 		},
@@ -51,6 +59,17 @@ func resourceUser() *schema.Resource {
 				Type:     schema.TypeString,
 				Required: true,
 			},
+			"password_mode": {
+				Type:         schema.TypeString,
+				Optional:     true,
+				Default:      "generated",
+				ValidateFunc: validation.StringInSlice([]string{"generated", "managed", "none"}, false),
+			},
+			"password": {
+				Type:      schema.TypeString,
+				Optional:  true,
+				Sensitive: true,
+			},
 			"password_length": {
 				Type:         schema.TypeInt,
 				Optional:     true,
@@ -72,13 +91,18 @@ const passwordCharset = "abcdefghijklmnopqrstuvwxyz" +
 	"0123456789" +
 	"@^*_-[]"
 
-func generateUserPassword(length int) string {
-	var seededRand *rand.Rand = rand.New(rand.NewSource(time.Now().UnixNano()))
+// generateUserPassword returns a random password. It uses crypto/rand, so passwords generated in
+// quick succession, like the throwaway password on create and the actual one, are unrelated.
+func generateUserPassword(length int) (string, error) {
 	b := make([]byte, length)
 	for i := range b {
-		b[i] = passwordCharset[seededRand.Intn(len(passwordCharset))]
+		n, err := rand.Int(rand.Reader, big.NewInt(int64(len(passwordCharset))))
+		if err != nil {
+			return "", err
+		}
+		b[i] = passwordCharset[n.Int64()]
 	}
-	return string(b)
+	return string(b), nil
 }
 
 func newUserFromResource(d *schema.ResourceData) *User {
@@ -117,6 +141,12 @@ func resourceUserUpdate(d *schema.ResourceData, m interface{}) error {
 		return err
 	}
 
+	if d.Get("password_mode").(string) == "managed" && (d.HasChange("password") || d.HasChange("password_mode")) {
+		if err := setUserPassword(client, user.Name, d.Get("password").(string)); err != nil {
+			return err
+		}
+	}
+
 	return resourceUserRead(d, m)
 }
 
@@ -124,13 +154,17 @@ func resourceUserCreate(d *schema.ResourceData, m interface{}) error {
 	client := m.(*BitbucketServerProvider).BitbucketClient
 	user := newUserFromResource(d)
 
-	passwordLength := d.Get("password_length").(int)
-	initialPassword := generateUserPassword(passwordLength)
-	d.Set("initial_password", initialPassword)
+	placeholder, err := generateUserPassword(64)
+	if err != nil {
+		return err
+	}
 
-	_, err := client.Post(fmt.Sprintf("/rest/api/1.0/admin/users?name=%s&password=%s&displayName=%s&emailAddress=%s",
+	// the create endpoint only takes the password in the query string, which ends up in access logs.
+	// The user is created with a throwaway password that is replaced right away through the credentials
+	// endpoint, which takes the password in the request body.
+	_, err = client.Post(fmt.Sprintf("/rest/api/1.0/admin/users?name=%s&password=%s&displayName=%s&emailAddress=%s",
 		url.QueryEscape(user.Name),
-		url.QueryEscape(initialPassword),
+		url.QueryEscape(placeholder),
 		url.QueryEscape(user.DisplayName),
 		url.QueryEscape(user.EmailAddress),
 	), nil)
@@ -139,11 +173,75 @@ func resourceUserCreate(d *schema.ResourceData, m interface{}) error {
 		return err
 	}
 
+	var password string
+	switch d.Get("password_mode").(string) {
+	case "generated":
+		password, err = generateUserPassword(d.Get("password_length").(int))
+	case "managed":
+		password = d.Get("password").(string)
+	default:
+		// directory-backed users never log in with their internal password, so it is never revealed
+		password, err = generateUserPassword(64)
+	}
+	if err == nil {
+		err = setUserPassword(client, user.Name, password)
+	}
+	if err != nil {
+		// never leave the user behind with the throwaway password from the access logs
+		if _, deleteErr := client.Delete(fmt.Sprintf("/rest/api/1.0/admin/users?name=%s", url.QueryEscape(user.Name))); deleteErr != nil {
+			return fmt.Errorf("%s, and deleting the user %s failed: %s", err, user.Name, deleteErr)
+		}
+		return err
+	}
+
 	d.SetId(user.Name)
 
+	if d.Get("password_mode").(string) == "generated" {
+		_ = d.Set("initial_password", password)
+	}
+
 	return resourceUserRead(d, m)
 }
 
+func resourceUserCustomizeDiff(d *schema.ResourceDiff, m interface{}) error {
+	// a password interpolated from another resource is only known at apply time
+	if !d.NewValueKnown("password") || !d.NewValueKnown("password_mode") {
+		return nil
+	}
+	return validateUserPassword(d.Get("password").(string), d.Get("password_mode").(string))
+}
+
+// validateUserPassword checks that a password is given if and only if it is managed by the caller.
+func validateUserPassword(password string, mode string) error {
+	switch mode {
+	case "managed":
+		if password == "" {
+			return fmt.Errorf("password must be set when password_mode is managed")
+		}
+	default:
+		if password != "" {
+			return fmt.Errorf("password can only be set when password_mode is managed")
+		}
+	}
+	return nil
+}
+
+// setUserPassword sets the password of the user through the admin credentials endpoint, which
+// takes the password in the request body.
+func setUserPassword(client *BitbucketClient, name string, password string) error {
+	bytedata, err := json.Marshal(UserCredentials{
+		Name:            name,
+		Password:        password,
+		PasswordConfirm: password,
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = client.Put("/rest/api/1.0/admin/users/credentials", bytes.NewBuffer(bytedata))
+	return err
+}
+
 func resourceUserRead(d *schema.ResourceData, m interface{}) error {
 	id := d.Id()
 	if id != "" {
@@ -158,7 +256,7 @@ func resourceUserRead(d *schema.ResourceData, m interface{}) error {
 	))
 
 	if err != nil {
-		return err
+		return removeIfNotFound(d, err)
 	}
 
 	if req.StatusCode == 200 {
@@ -198,6 +296,9 @@ func resourceUserExists(d *schema.ResourceData, m interface{}) (bool, error) {
 		url.PathEscape(name),
 	))
 
+	if IsNotFound(err) {
+		return false, nil
+	}
 	if err != nil {
 		return false, fmt.Errorf("failed to get user %s from bitbucket: %+v", name, err)
 	}
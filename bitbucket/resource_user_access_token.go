@@ -32,6 +32,7 @@ func resourceUserAccessToken() *schema.Resource {
 		Importer: &schema.ResourceImporter{
 			State: schema.ImportStatePassthrough,
 		},
+		CustomizeDiff: resourceUserAccessTokenCustomizeDiff,
 
 		Schema: map[string]*schema.Schema{
 			"user": {
@@ -65,6 +66,15 @@ func resourceUserAccessToken() *schema.Resource {
 	}
 }
 
+// resourceUserAccessTokenCustomizeDiff fails the plan on servers without personal access tokens.
+func resourceUserAccessTokenCustomizeDiff(d *schema.ResourceDiff, m interface{}) error {
+	serverInfo, err := m.(*BitbucketServerProvider).serverInfo()
+	if err != nil {
+		return err
+	}
+	return serverInfo.Require(CapabilityAccessTokens)
+}
+
 func resourceUserAccessTokenCreate(d *schema.ResourceData, m interface{}) error {
 	client := m.(*BitbucketServerProvider).BitbucketClient
 
@@ -137,7 +147,7 @@ func resourceUserAccessTokenRead(d *schema.ResourceData, m interface{}) error {
 	))
 
 	if err != nil {
-		return err
+		return removeIfNotFound(d, err)
 	}
 
 	var accessTokenResponse AccessTokenResponse
@@ -166,6 +176,9 @@ func resourceUserAccessTokenExists(d *schema.ResourceData, m interface{}) (bool,
 		d.Id(),
 	))
 
+	if IsNotFound(err) {
+		return false, nil
+	}
 	if err != nil {
 		return false, fmt.Errorf("failed to get access token %s for user %s from bitbucket: %+v", d.Id(), d.Get("user").(string), err)
 	}
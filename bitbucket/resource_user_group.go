@@ -94,7 +94,7 @@ func resourceUserGroupRead(d *schema.ResourceData, m interface{}) error {
 
 	groupUsers, err := readGroupUsers(m, userGroup.Group, userGroup.User)
 	if err != nil {
-		return err
+		return removeIfNotFound(d, err)
 	}
 
 	// API only filters but we need to find an exact match
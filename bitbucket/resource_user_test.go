@@ -1,13 +1,18 @@
 package bitbucket
 
 import (
+	"encoding/json"
 	"fmt"
 	"math/rand"
+	"net/http"
+	"net/http/httptest"
 	"strings"
 	"testing"
 	"time"
 
+	"github.com/hashicorp/terraform/config"
 	"github.com/hashicorp/terraform/helper/resource"
+	"github.com/hashicorp/terraform/helper/schema"
 	"github.com/hashicorp/terraform/terraform"
 )
 
@@ -65,3 +70,183 @@ func testAccCheckBitbucketUserDestroy(s *terraform.State) error {
 
 	return nil
 }
+
+func TestAccBitbucketUser_managedPassword(t *testing.T) {
+	userRand := fmt.Sprintf("%v", rand.New(rand.NewSource(time.Now().UnixNano())).Int())
+	config := fmt.Sprintf(`
+		resource "bitbucketserver_user" "test" {
+			name = "admin %v"
+			display_name = "Admin %v"
+			email_address = "admin%v@example.com"
+			password_mode = "managed"
+			password = "Managed-Password-1"
+		}
+	`, userRand, userRand, userRand)
+
+	configModified := strings.ReplaceAll(config, "Managed-Password-1", "Managed-Password-2")
+
+	resource.Test(t, resource.TestCase{
+		PreCheck:     func() { testAccPreCheck(t) },
+		Providers:    testAccProviders,
+		CheckDestroy: testAccCheckBitbucketUserDestroy,
+		Steps: []resource.TestStep{
+			{
+				Config: config,
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttr("bitbucketserver_user.test", "password_mode", "managed"),
+					resource.TestCheckResourceAttr("bitbucketserver_user.test", "initial_password", ""),
+				),
+			},
+			{
+				Config: configModified,
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttr("bitbucketserver_user.test", "password", "Managed-Password-2"),
+				),
+			},
+		},
+	})
+}
+
+func TestResourceUserCreate_PasswordNotInQuery(t *testing.T) {
+	var credentials UserCredentials
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == "POST" && r.URL.Path == "/rest/api/1.0/admin/users":
+			if strings.Contains(r.URL.RawQuery, "Managed-Password") {
+				t.Errorf("password sent in query string: %s", r.URL.RawQuery)
+			}
+		case r.Method == "PUT" && r.URL.Path == "/rest/api/1.0/admin/users/credentials":
+			_ = json.NewDecoder(r.Body).Decode(&credentials)
+		case r.Method == "GET":
+			fmt.Fprint(w, `{"name": "jdoe", "emailAddress": "jdoe@example.com", "displayName": "John Doe", "id": 1}`)
+		default:
+			t.Errorf("unexpected request %s %s", r.Method, r.URL)
+		}
+	}))
+	defer server.Close()
+
+	provider := newTestProvider(server)
+
+	d := schema.TestResourceDataRaw(t, resourceUser().Schema, map[string]interface{}{
+		"name":          "jdoe",
+		"display_name":  "John Doe",
+		"email_address": "jdoe@example.com",
+		"password_mode": "managed",
+		"password":      "Managed-Password-1",
+	})
+
+	if err := resourceUserCreate(d, provider); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	if credentials.Name != "jdoe" || credentials.Password != "Managed-Password-1" || credentials.PasswordConfirm != "Managed-Password-1" {
+		t.Fatalf("unexpected credentials %+v", credentials)
+	}
+}
+
+func TestResourceUserCreate_PasswordFailureDeletesUser(t *testing.T) {
+	deleted := false
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == "POST" && r.URL.Path == "/rest/api/1.0/admin/users":
+		case r.Method == "PUT" && r.URL.Path == "/rest/api/1.0/admin/users/credentials":
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprint(w, `{"errors": [{"message": "password does not meet the requirements"}]}`)
+		case r.Method == "DELETE" && r.URL.Path == "/rest/api/1.0/admin/users" && r.URL.Query().Get("name") == "jdoe":
+			deleted = true
+		default:
+			t.Errorf("unexpected request %s %s", r.Method, r.URL)
+		}
+	}))
+	defer server.Close()
+
+	d := schema.TestResourceDataRaw(t, resourceUser().Schema, map[string]interface{}{
+		"name":          "jdoe",
+		"display_name":  "John Doe",
+		"email_address": "jdoe@example.com",
+		"password_mode": "managed",
+		"password":      "weak",
+	})
+
+	if err := resourceUserCreate(d, newTestProvider(server)); err == nil {
+		t.Fatal("expected the credentials error")
+	}
+
+	if !deleted {
+		t.Fatal("expected the user to be deleted")
+	}
+	if d.Id() != "" {
+		t.Fatalf("expected no ID, got %s", d.Id())
+	}
+}
+
+func TestResourceUserCreate_PasswordModeNone(t *testing.T) {
+	// the throwaway password of the create request shows up in access logs, so it is rotated as well
+	var credentials UserCredentials
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == "POST" && r.URL.Path == "/rest/api/1.0/admin/users":
+		case r.Method == "PUT" && r.URL.Path == "/rest/api/1.0/admin/users/credentials":
+			_ = json.NewDecoder(r.Body).Decode(&credentials)
+		case r.Method == "GET":
+			fmt.Fprint(w, `{"name": "jdoe", "emailAddress": "jdoe@example.com", "displayName": "John Doe", "id": 1}`)
+		default:
+			t.Errorf("unexpected request %s %s", r.Method, r.URL)
+		}
+	}))
+	defer server.Close()
+
+	d := schema.TestResourceDataRaw(t, resourceUser().Schema, map[string]interface{}{
+		"name":          "jdoe",
+		"display_name":  "John Doe",
+		"email_address": "jdoe@example.com",
+		"password_mode": "none",
+	})
+
+	if err := resourceUserCreate(d, newTestProvider(server)); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	if credentials.Name != "jdoe" || credentials.Password == "" || credentials.Password != credentials.PasswordConfirm {
+		t.Fatalf("expected the password to be rotated, got %+v", credentials)
+	}
+	if password := d.Get("initial_password").(string); password != "" {
+		t.Fatalf("expected no initial_password, got %s", password)
+	}
+}
+
+func TestResourceUserDiff_PasswordMode(t *testing.T) {
+	cases := []struct {
+		config map[string]interface{}
+		err    string
+	}{
+		{map[string]interface{}{"password_mode": "managed"}, "password must be set"},
+		{map[string]interface{}{"password_mode": "generated", "password": "secret"}, "password can only be set"},
+		{map[string]interface{}{"password": "secret"}, "password can only be set"},
+		{map[string]interface{}{"password_mode": "managed", "password": "secret"}, ""},
+		{map[string]interface{}{"password_mode": "none"}, ""},
+	}
+
+	for _, c := range cases {
+		raw := map[string]interface{}{
+			"name":          "jdoe",
+			"display_name":  "John Doe",
+			"email_address": "jdoe@example.com",
+		}
+		for key, value := range c.config {
+			raw[key] = value
+		}
+		rawConfig, err := config.NewRawConfig(raw)
+		if err != nil {
+			t.Fatalf("err: %s", err)
+		}
+
+		_, err = resourceUser().Diff(nil, terraform.NewResourceConfig(rawConfig), &BitbucketServerProvider{})
+		if c.err == "" && err != nil {
+			t.Errorf("%v: unexpected error %s", c.config, err)
+		}
+		if c.err != "" && (err == nil || !strings.Contains(err.Error(), c.err)) {
+			t.Errorf("%v: expected an error containing %q, got %v", c.config, c.err, err)
+		}
+	}
+}
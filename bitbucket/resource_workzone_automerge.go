@@ -10,7 +10,6 @@ import (
 
 	"io/ioutil"
 
-	"net/http"
 	"net/url"
 
 	"github.com/hashicorp/terraform/helper/resource"
@@ -51,6 +50,9 @@ func resourceWorkzoneAutoMerge() *schema.Resource {
 		Importer: &schema.ResourceImporter{
 			State: schema.ImportStatePassthrough,
 		},
+		Timeouts: &schema.ResourceTimeout{
+			Create: schema.DefaultTimeout(time.Minute),
+		},
 
 		Schema: map[string]*schema.Schema{
 			"project": {
@@ -187,14 +189,14 @@ func resourceWorkzoneAutoMergeCreate(d *schema.ResourceData, m interface{}) erro
 
 	// When you install the Workzone plugin, then create a repository and immediately after that try to update the Workzone settings,
 	// the API can return 404. That's why the POST call is wrapped into the retry function.
-	err = resource.Retry(time.Minute,
+	err = resource.Retry(d.Timeout(schema.TimeoutCreate),
 		func() *resource.RetryError {
 			_, err = client.Post(fmt.Sprintf("/rest/workzoneresource/1.0/branch/automerge/%s/%s",
 				wz.Project,
 				wz.Repository,
 			), bytes.NewBuffer(bytedata))
 			if err != nil {
-				return resource.RetryableError(fmt.Errorf("waiting for workzone settings to become available"))
+				return retryUnlessCancelled(client, err, fmt.Errorf("waiting for workzone settings to become available"))
 			} else {
 				return nil
 			}
@@ -231,13 +233,7 @@ func resourceWorkzoneAutoMergeRead(d *schema.ResourceData, m interface{}) error
 	))
 
 	if err != nil {
-		return err
-	}
-
-	if req.StatusCode == http.StatusNotFound {
-		log.Printf("[WARN] Workzone Reviewers object (%s) not found, removing from state", d.Id())
-		d.SetId("")
-		return nil
+		return removeIfNotFound(d, err)
 	}
 
 	var wz []WorkzoneAutoMerge
@@ -298,5 +294,5 @@ func resourceWorkzoneAutoMergeDelete(d *schema.ResourceData, m interface{}) erro
 		url.QueryEscape(repository),
 	), bytes.NewBuffer(bytedata))
 
-	return err
+	return ignoreNotFound(err)
 }
@@ -10,7 +10,6 @@ import (
 
 	"io/ioutil"
 
-	"net/http"
 	"net/url"
 
 	"github.com/hashicorp/terraform/helper/resource"
@@ -141,6 +140,9 @@ func resourceWorkzoneReviewers() *schema.Resource {
 		Importer: &schema.ResourceImporter{
 			State: schema.ImportStatePassthrough,
 		},
+		Timeouts: &schema.ResourceTimeout{
+			Create: schema.DefaultTimeout(time.Minute),
+		},
 
 		Schema: map[string]*schema.Schema{
 			"project": {
@@ -289,14 +291,14 @@ func resourceWorkzoneReviewersCreate(d *schema.ResourceData, m interface{}) erro
 
 	// When you install the Workzone plugin, then create a repository and immediately after that try to update the Workzone settings,
 	// the API can return 404. That's why the POST call is wrapped into the retry function.
-	err = resource.Retry(time.Minute,
+	err = resource.Retry(d.Timeout(schema.TimeoutCreate),
 		func() *resource.RetryError {
 			_, err = client.Post(fmt.Sprintf("/rest/workzoneresource/1.0/branch/reviewers/%s/%s",
 				wz.Project,
 				wz.Repository,
 			), bytes.NewBuffer(bytedata))
 			if err != nil {
-				return resource.RetryableError(fmt.Errorf("waiting for workzone settings to become available"))
+				return retryUnlessCancelled(client, err, fmt.Errorf("waiting for workzone settings to become available"))
 			} else {
 				return nil
 			}
@@ -333,13 +335,7 @@ func resourceWorkzoneReviewersRead(d *schema.ResourceData, m interface{}) error
 	))
 
 	if err != nil {
-		return err
-	}
-
-	if req.StatusCode == http.StatusNotFound {
-		log.Printf("[WARN] Workzone Reviewers object (%s) not found, removing from state", d.Id())
-		d.SetId("")
-		return nil
+		return removeIfNotFound(d, err)
 	}
 
 	var wz []WorkzoneReviewers
@@ -391,5 +387,5 @@ func resourceWorkzoneReviewersDelete(d *schema.ResourceData, m interface{}) erro
 		url.QueryEscape(repository),
 	), bytes.NewBuffer(bytedata))
 
-	return err
+	return ignoreNotFound(err)
 }
@@ -5,8 +5,6 @@ import (
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
-	"log"
-	"net/http"
 	"net/url"
 	"strings"
 	"time"
@@ -35,6 +33,9 @@ func resourceWorkzoneWorkflow() *schema.Resource {
 		Importer: &schema.ResourceImporter{
 			State: schema.ImportStatePassthrough,
 		},
+		Timeouts: &schema.ResourceTimeout{
+			Create: schema.DefaultTimeout(time.Minute),
+		},
 
 		Schema: map[string]*schema.Schema{
 			"project": {
@@ -82,14 +83,14 @@ func resourceWorkzoneWorkflowCreate(d *schema.ResourceData, m interface{}) error
 
 	// When you install the Workzone plugin, then create a repository and immediately after that try to update the Workzone settings,
 	// the API can return 404. That's why the POST call is wrapped into the retry function.
-	err = resource.Retry(time.Minute,
+	err = resource.Retry(d.Timeout(schema.TimeoutCreate),
 		func() *resource.RetryError {
 			_, err = client.Post(fmt.Sprintf("/rest/workzoneresource/1.0/workflow/%s/%s",
 				wz.Project,
 				wz.Repository,
 			), bytes.NewBuffer(bytedata))
 			if err != nil {
-				return resource.RetryableError(fmt.Errorf("waiting for workzone settings to become available"))
+				return retryUnlessCancelled(client, err, fmt.Errorf("waiting for workzone settings to become available"))
 			} else {
 				return nil
 			}
@@ -136,13 +137,7 @@ func resourceWorkzoneWorkflowRead(d *schema.ResourceData, m interface{}) error {
 	))
 
 	if err != nil {
-		return err
-	}
-
-	if req.StatusCode == http.StatusNotFound {
-		log.Printf("[WARN] Workzone Reviewers object (%s) not found, removing from state", d.Id())
-		d.SetId("")
-		return nil
+		return removeIfNotFound(d, err)
 	}
 
 	var wz WorkzoneWorkflow
@@ -174,5 +169,5 @@ func resourceWorkzoneWorkflowDelete(d *schema.ResourceData, m interface{}) error
 		url.QueryEscape(repository),
 	))
 
-	return err
+	return ignoreNotFound(err)
 }
@@ -1,12 +1,17 @@
 package bitbucket
 
 import (
+	"context"
 	"fmt"
 	"math/rand"
+	"net/http"
+	"net/http/httptest"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/hashicorp/terraform/helper/resource"
+	"github.com/hashicorp/terraform/helper/schema"
 )
 
 func TestAccBitbucketResourceWorkzoneWorkflow_requiredArgumentsOnly(t *testing.T) {
@@ -74,3 +79,33 @@ func TestAccBitbucketResourceWorkzoneWorkflow_allArguments(t *testing.T) {
 		},
 	})
 }
+
+func TestResourceWorkzoneWorkflowCreate_Cancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Terraform is interrupted while it waits for the Workzone settings
+		cancel()
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer server.Close()
+
+	provider := newTestProvider(server)
+	provider.BitbucketClient.Context = ctx
+
+	d := schema.TestResourceDataRaw(t, resourceWorkzoneWorkflow().Schema, map[string]interface{}{
+		"project":    "TEST",
+		"repository": "repo",
+	})
+
+	result := make(chan error, 1)
+	go func() { result <- resourceWorkzoneWorkflowCreate(d, provider) }()
+
+	select {
+	case err := <-result:
+		if err == nil || strings.Contains(err.Error(), "waiting for workzone settings") {
+			t.Fatalf("expected the create to stop with the request error, got %v", err)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("expected the create to stop retrying once cancelled")
+	}
+}
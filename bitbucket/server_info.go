@@ -0,0 +1,131 @@
+package bitbucket
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ServerVersion is the version of a Bitbucket server, e.g. 7.21.0.
+type ServerVersion struct {
+	Major int
+	Minor int
+	Patch int
+}
+
+// ParseServerVersion parses a version as reported by the application properties. Suffixes such
+// as -SNAPSHOT or -rc1 are ignored.
+func ParseServerVersion(version string) (ServerVersion, error) {
+	var parsed ServerVersion
+	parts := strings.SplitN(version, ".", 3)
+	targets := []*int{&parsed.Major, &parsed.Minor, &parsed.Patch}
+
+	for i, part := range parts {
+		digits := strings.IndexFunc(part, func(r rune) bool { return r < '0' || r > '9' })
+		if digits >= 0 {
+			part = part[:digits]
+		}
+
+		number, err := strconv.Atoi(part)
+		if err != nil {
+			return ServerVersion{}, fmt.Errorf("unable to parse Bitbucket version %q", version)
+		}
+		*targets[i] = number
+	}
+
+	return parsed, nil
+}
+
+// AtLeast reports whether the version is the same as or newer than other.
+func (v ServerVersion) AtLeast(other ServerVersion) bool {
+	if v.Major != other.Major {
+		return v.Major > other.Major
+	}
+	if v.Minor != other.Minor {
+		return v.Minor > other.Minor
+	}
+	return v.Patch >= other.Patch
+}
+
+func (v ServerVersion) String() string {
+	return fmt.Sprintf("%d.%d.%d", v.Major, v.Minor, v.Patch)
+}
+
+// Capability is a feature of the Bitbucket API that is only available from a certain server
+// version on.
+type Capability string
+
+const (
+	// CapabilityAccessTokens covers personal access tokens, both for authenticating the provider
+	// and the /rest/access-tokens/1.0 endpoints.
+	CapabilityAccessTokens Capability = "personal access tokens"
+	// CapabilityRepositoryArchiving covers the archived flag of repositories.
+	CapabilityRepositoryArchiving Capability = "repository archiving"
+	// CapabilityDefaultBranchEndpoint covers the repository default-branch endpoint, which replaces
+	// the deprecated branches/default endpoint.
+	CapabilityDefaultBranchEndpoint Capability = "repository default-branch endpoint"
+)
+
+// capabilities is the registry of the minimum server version of each capability.
+var capabilities = map[Capability]ServerVersion{
+	CapabilityAccessTokens:          {Major: 5, Minor: 5},
+	CapabilityRepositoryArchiving:   {Major: 8},
+	CapabilityDefaultBranchEndpoint: {Major: 7, Minor: 5},
+}
+
+// ServerInfo describes the Bitbucket server the provider is configured against.
+type ServerInfo struct {
+	Version     ServerVersion
+	BuildNumber int
+	DisplayName string
+}
+
+// newServerInfo parses the version information of the application properties.
+func newServerInfo(properties *ApplicationProperties) (*ServerInfo, error) {
+	version, err := ParseServerVersion(properties.Version)
+	if err != nil {
+		return nil, err
+	}
+
+	buildNumber, err := strconv.Atoi(properties.BuildNumber)
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse Bitbucket build number %q", properties.BuildNumber)
+	}
+
+	return &ServerInfo{
+		Version:     version,
+		BuildNumber: buildNumber,
+		DisplayName: properties.DisplayName,
+	}, nil
+}
+
+// serverInfo returns the server information fetched at configure time, or fetches it if the
+// provider was set up without it.
+func (p *BitbucketServerProvider) serverInfo() (*ServerInfo, error) {
+	if p.ServerInfo != nil {
+		return p.ServerInfo, nil
+	}
+
+	applicationProperties, err := readApplicationProperties(p.BitbucketClient)
+	if err != nil {
+		return nil, err
+	}
+	return newServerInfo(applicationProperties)
+}
+
+// Supports reports whether the server provides the capability. An unknown server is assumed to
+// support everything.
+func (s *ServerInfo) Supports(capability Capability) bool {
+	if s == nil {
+		return true
+	}
+	return s.Version.AtLeast(capabilities[capability])
+}
+
+// Require returns an error if the server does not provide the capability.
+func (s *ServerInfo) Require(capability Capability) error {
+	if s.Supports(capability) {
+		return nil
+	}
+	return fmt.Errorf("%s requires Bitbucket >= %s, the server runs %s", capability, capabilities[capability], s.Version)
+}
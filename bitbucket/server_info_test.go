@@ -0,0 +1,64 @@
+package bitbucket
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseServerVersion(t *testing.T) {
+	cases := map[string]ServerVersion{
+		"7.21.0":          {Major: 7, Minor: 21, Patch: 0},
+		"8.9":             {Major: 8, Minor: 9},
+		"8.0.0-SNAPSHOT":  {Major: 8, Minor: 0, Patch: 0},
+		"5.16.10-rc1":     {Major: 5, Minor: 16, Patch: 10},
+		"6.10.0.20200101": {Major: 6, Minor: 10, Patch: 0},
+	}
+
+	for input, expected := range cases {
+		version, err := ParseServerVersion(input)
+		if err != nil {
+			t.Errorf("%s: err: %s", input, err)
+			continue
+		}
+		if version != expected {
+			t.Errorf("%s: expected %v, got %v", input, expected, version)
+		}
+	}
+
+	if _, err := ParseServerVersion("unknown"); err == nil {
+		t.Error("expected invalid version to fail")
+	}
+}
+
+func TestServerVersion_AtLeast(t *testing.T) {
+	version := ServerVersion{Major: 7, Minor: 21, Patch: 3}
+
+	for _, other := range []ServerVersion{{7, 21, 3}, {7, 21, 0}, {7, 5, 9}, {6, 99, 99}} {
+		if !version.AtLeast(other) {
+			t.Errorf("expected %s to be at least %s", version, other)
+		}
+	}
+	for _, other := range []ServerVersion{{7, 21, 4}, {7, 22, 0}, {8, 0, 0}} {
+		if version.AtLeast(other) {
+			t.Errorf("expected %s to be older than %s", version, other)
+		}
+	}
+}
+
+func TestServerInfo_Require(t *testing.T) {
+	old := &ServerInfo{Version: ServerVersion{Major: 5, Minor: 4}}
+	err := old.Require(CapabilityAccessTokens)
+	if err == nil || !strings.Contains(err.Error(), "requires Bitbucket >= 5.5.0") {
+		t.Fatalf("expected capability error, got %v", err)
+	}
+
+	current := &ServerInfo{Version: ServerVersion{Major: 8, Minor: 9}}
+	if err := current.Require(CapabilityAccessTokens); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	var unknown *ServerInfo
+	if !unknown.Supports(CapabilityAccessTokens) {
+		t.Fatal("expected unknown servers to support every capability")
+	}
+}
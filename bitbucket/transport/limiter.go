@@ -0,0 +1,141 @@
+package transport
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Limiter bounds the number of requests in flight and paces request starts to a maximum rate.
+// A nil Limiter does not limit.
+type Limiter struct {
+	slots    chan struct{}
+	interval time.Duration
+
+	mutex sync.Mutex
+	next  time.Time
+}
+
+// NewLimiter returns a limiter allowing maxConcurrent requests in flight and perSecond request
+// starts per second. A value of 0 disables the respective limit, nil is returned if both are 0.
+func NewLimiter(maxConcurrent int, perSecond float64) *Limiter {
+	if maxConcurrent <= 0 && perSecond <= 0 {
+		return nil
+	}
+
+	l := &Limiter{}
+	if maxConcurrent > 0 {
+		l.slots = make(chan struct{}, maxConcurrent)
+	}
+	if perSecond > 0 {
+		l.interval = time.Duration(float64(time.Second) / perSecond)
+	}
+	return l
+}
+
+// Acquire blocks until a request may be sent or the context is done. The returned function
+// must be called once the request has completed.
+func (l *Limiter) Acquire(ctx context.Context) (func(), error) {
+	if l == nil {
+		return func() {}, nil
+	}
+
+	if l.slots != nil {
+		select {
+		case l.slots <- struct{}{}:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+
+	release := func() {
+		if l.slots != nil {
+			<-l.slots
+		}
+	}
+
+	if wait := l.reserve(); wait > 0 {
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			release()
+			return nil, ctx.Err()
+		case <-timer.C:
+		}
+	}
+
+	return release, nil
+}
+
+// reserve books the next start time and returns how long the caller has to wait for it.
+func (l *Limiter) reserve() time.Duration {
+	if l.interval == 0 {
+		return 0
+	}
+
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	now := time.Now()
+	if l.next.Before(now) {
+		l.next = now
+	}
+	wait := l.next.Sub(now)
+	l.next = l.next.Add(l.interval)
+	return wait
+}
+
+type limiterKey struct{}
+
+// WithLimiter returns a copy of ctx carrying the limiter for requests sent through a
+// LimitTransport.
+func WithLimiter(ctx context.Context, l *Limiter) context.Context {
+	return context.WithValue(ctx, limiterKey{}, l)
+}
+
+// LimitTransport acquires the limiter carried by the request context for every round trip. Placed
+// below a RetryTransport, retries are throttled like first attempts and no slot is held while
+// waiting between attempts. Requests without a limiter are sent as-is.
+type LimitTransport struct {
+	Base http.RoundTripper
+}
+
+func (t *LimitTransport) base() http.RoundTripper {
+	if t.Base != nil {
+		return t.Base
+	}
+	return http.DefaultTransport
+}
+
+func (t *LimitTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	l, _ := req.Context().Value(limiterKey{}).(*Limiter)
+	release, err := l.Acquire(req.Context())
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := t.base().RoundTrip(req)
+	if err != nil {
+		release()
+		return resp, err
+	}
+
+	// the slot is held until the response body has been consumed
+	resp.Body = &releaseOnClose{ReadCloser: resp.Body, release: release}
+	return resp, nil
+}
+
+type releaseOnClose struct {
+	io.ReadCloser
+	release func()
+	once    sync.Once
+}
+
+func (b *releaseOnClose) Close() error {
+	err := b.ReadCloser.Close()
+	b.once.Do(b.release)
+	return err
+}
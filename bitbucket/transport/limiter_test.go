@@ -0,0 +1,134 @@
+package transport
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestLimiter_MaxConcurrent(t *testing.T) {
+	limiter := NewLimiter(2, 0)
+
+	var inFlight, peak int32
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			release, err := limiter.Acquire(context.Background())
+			if err != nil {
+				t.Errorf("err: %s", err)
+				return
+			}
+			defer release()
+
+			current := atomic.AddInt32(&inFlight, 1)
+			for {
+				max := atomic.LoadInt32(&peak)
+				if current <= max || atomic.CompareAndSwapInt32(&peak, max, current) {
+					break
+				}
+			}
+			time.Sleep(5 * time.Millisecond)
+			atomic.AddInt32(&inFlight, -1)
+		}()
+	}
+	wg.Wait()
+
+	if peak > 2 {
+		t.Fatalf("expected at most 2 requests in flight, got %d", peak)
+	}
+}
+
+func TestLimiter_RequestsPerSecond(t *testing.T) {
+	limiter := NewLimiter(0, 100)
+
+	start := time.Now()
+	for i := 0; i < 5; i++ {
+		release, err := limiter.Acquire(context.Background())
+		if err != nil {
+			t.Fatalf("err: %s", err)
+		}
+		release()
+	}
+
+	// the first request starts immediately, the other four are paced 10ms apart
+	if elapsed := time.Since(start); elapsed < 40*time.Millisecond {
+		t.Fatalf("expected requests to be paced, took %s", elapsed)
+	}
+}
+
+func TestLimiter_ContextCancelled(t *testing.T) {
+	limiter := NewLimiter(1, 0)
+
+	release, err := limiter.Acquire(context.Background())
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer release()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	if _, err := limiter.Acquire(ctx); err != context.DeadlineExceeded {
+		t.Fatalf("expected deadline exceeded, got %v", err)
+	}
+}
+
+func TestLimiter_Nil(t *testing.T) {
+	if limiter := NewLimiter(0, 0); limiter != nil {
+		t.Fatal("expected no limiter without limits")
+	}
+
+	var limiter *Limiter
+	release, err := limiter.Acquire(context.Background())
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	release()
+}
+
+func TestLimitTransport_PacesRetries(t *testing.T) {
+	var starts []time.Time
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		starts = append(starts, time.Now())
+		if len(starts) < 3 {
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	client := &http.Client{Transport: &RetryTransport{
+		Base:       &LimitTransport{},
+		MaxRetries: 3,
+		WaitMin:    time.Millisecond,
+		WaitMax:    time.Millisecond,
+	}}
+
+	req, err := http.NewRequestWithContext(WithLimiter(context.Background(), NewLimiter(1, 20)), "GET", server.URL, nil)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	resp.Body.Close()
+
+	if len(starts) != 3 {
+		t.Fatalf("expected 3 attempts, got %d", len(starts))
+	}
+	// every retry waits for its own slot of the 20 per second budget
+	for i := 1; i < len(starts); i++ {
+		if gap := starts[i].Sub(starts[i-1]); gap < 40*time.Millisecond {
+			t.Errorf("attempt %d started %s after the previous one", i+1, gap)
+		}
+	}
+}
@@ -0,0 +1,45 @@
+package transport
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"golang.org/x/net/http/httpproxy"
+)
+
+// ProxyOptions describes the proxy used by a transport. Without a URL the proxy is taken
+// from the HTTP_PROXY, HTTPS_PROXY and NO_PROXY environment variables.
+type ProxyOptions struct {
+	URL     string
+	NoProxy []string
+}
+
+// NewProxyFunc returns a proxy function for http.Transport. Hosts matching an entry of
+// NoProxy are reached directly, using the same matching rules as the NO_PROXY variable.
+func NewProxyFunc(opts ProxyOptions) (func(*http.Request) (*url.URL, error), error) {
+	config := httpproxy.FromEnvironment()
+
+	if opts.URL != "" {
+		proxyURL, err := url.Parse(opts.URL)
+		if err != nil {
+			return nil, fmt.Errorf("invalid proxy URL %q: %s", opts.URL, err)
+		}
+		if proxyURL.Scheme == "" || proxyURL.Host == "" {
+			return nil, fmt.Errorf("invalid proxy URL %q: scheme and host are required", opts.URL)
+		}
+
+		config.HTTPProxy = opts.URL
+		config.HTTPSProxy = opts.URL
+	}
+
+	if len(opts.NoProxy) > 0 {
+		config.NoProxy = strings.Join(opts.NoProxy, ",")
+	}
+
+	proxyFunc := config.ProxyFunc()
+	return func(req *http.Request) (*url.URL, error) {
+		return proxyFunc(req.URL)
+	}, nil
+}
@@ -0,0 +1,44 @@
+package transport
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestNewProxyFunc(t *testing.T) {
+	proxy, err := NewProxyFunc(ProxyOptions{
+		URL:     "http://proxy.example.com:3128",
+		NoProxy: []string{"bitbucket.example.com", ".internal.example.com"},
+	})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	cases := map[string]string{
+		"https://marketplace.atlassian.com/rest/2/addons": "http://proxy.example.com:3128",
+		"https://bitbucket.example.com/rest/api/1.0":      "",
+		"https://git.internal.example.com/rest/api/1.0":   "",
+	}
+
+	for target, expected := range cases {
+		req, _ := http.NewRequest("GET", target, nil)
+		proxyURL, err := proxy(req)
+		if err != nil {
+			t.Fatalf("%s: err: %s", target, err)
+		}
+
+		actual := ""
+		if proxyURL != nil {
+			actual = proxyURL.String()
+		}
+		if actual != expected {
+			t.Errorf("%s: expected proxy %q, got %q", target, expected, actual)
+		}
+	}
+}
+
+func TestNewProxyFunc_InvalidURL(t *testing.T) {
+	if _, err := NewProxyFunc(ProxyOptions{URL: "proxy.example.com"}); err == nil {
+		t.Error("expected error for proxy URL without scheme")
+	}
+}
@@ -0,0 +1,155 @@
+package transport
+
+import (
+	"io"
+	"io/ioutil"
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// RetryTransport retries requests that failed with a transient error, waiting with an
+// exponential backoff between attempts. Rate limited (429) requests are retried for any
+// method as the server did not process them, connection errors and 5xx responses only
+// for idempotent methods.
+type RetryTransport struct {
+	Base       http.RoundTripper
+	MaxRetries int
+	WaitMin    time.Duration
+	WaitMax    time.Duration
+}
+
+func (t *RetryTransport) base() http.RoundTripper {
+	if t.Base != nil {
+		return t.Base
+	}
+	return http.DefaultTransport
+}
+
+func (t *RetryTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	attempt := 0
+	for {
+		resp, err := t.base().RoundTrip(req)
+
+		if attempt >= t.MaxRetries || !shouldRetry(req, resp, err) {
+			return resp, err
+		}
+
+		if req.Body != nil && req.GetBody == nil {
+			// the body has already been consumed and cannot be replayed
+			return resp, err
+		}
+
+		wait := t.backoff(attempt, resp)
+		if resp != nil {
+			log.Printf("[DEBUG] Retrying %s %s in %s after status %d (attempt %d/%d)", req.Method, req.URL.Path, wait, resp.StatusCode, attempt+1, t.MaxRetries)
+			drainBody(resp)
+		} else {
+			log.Printf("[DEBUG] Retrying %s %s in %s after error %v (attempt %d/%d)", req.Method, req.URL.Path, wait, err, attempt+1, t.MaxRetries)
+		}
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-req.Context().Done():
+			timer.Stop()
+			return nil, req.Context().Err()
+		case <-timer.C:
+		}
+
+		req, err = rewind(req)
+		if err != nil {
+			return nil, err
+		}
+		attempt++
+	}
+}
+
+// backoff returns how long to wait before the next attempt, honouring a Retry-After
+// header when the server sent one. The wait is never longer than WaitMax.
+func (t *RetryTransport) backoff(attempt int, resp *http.Response) time.Duration {
+	if resp != nil {
+		if wait, ok := retryAfter(resp); ok {
+			if wait > t.WaitMax {
+				return t.WaitMax
+			}
+			return wait
+		}
+	}
+
+	if t.WaitMin <= 0 {
+		return 0
+	}
+
+	// compared as a float, as the exponential wait overflows a Duration after enough attempts
+	wait := float64(t.WaitMin) * math.Pow(2, float64(attempt))
+	if wait > float64(t.WaitMax) {
+		return t.WaitMax
+	}
+	return time.Duration(wait)
+}
+
+func shouldRetry(req *http.Request, resp *http.Response, err error) bool {
+	if err != nil {
+		return isIdempotent(req.Method) && req.Context().Err() == nil
+	}
+
+	switch resp.StatusCode {
+	case http.StatusTooManyRequests:
+		return true
+	case http.StatusInternalServerError, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return isIdempotent(req.Method)
+	}
+
+	return false
+}
+
+func isIdempotent(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodPut, http.MethodDelete:
+		return true
+	}
+	return false
+}
+
+func retryAfter(resp *http.Response) (time.Duration, bool) {
+	header := resp.Header.Get("Retry-After")
+	if header == "" {
+		return 0, false
+	}
+
+	if seconds, err := strconv.Atoi(header); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second, true
+	}
+
+	if date, err := http.ParseTime(header); err == nil {
+		wait := time.Until(date)
+		if wait < 0 {
+			wait = 0
+		}
+		return wait, true
+	}
+
+	return 0, false
+}
+
+func rewind(req *http.Request) (*http.Request, error) {
+	if req.GetBody == nil {
+		return req, nil
+	}
+
+	body, err := req.GetBody()
+	if err != nil {
+		return nil, err
+	}
+
+	clone := req.Clone(req.Context())
+	clone.Body = body
+	return clone, nil
+}
+
+func drainBody(resp *http.Response) {
+	_, _ = io.Copy(ioutil.Discard, resp.Body)
+	_ = resp.Body.Close()
+}
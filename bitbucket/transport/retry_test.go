@@ -0,0 +1,100 @@
+package transport
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestRetryTransport_RetriesRateLimitedRequests(t *testing.T) {
+	attempts := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		body, _ := ioutil.ReadAll(r.Body)
+		if string(body) != "payload" {
+			t.Errorf("unexpected body %q on attempt %d", body, attempts)
+		}
+		if attempts < 3 {
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	client := &http.Client{Transport: &RetryTransport{MaxRetries: 3, WaitMin: time.Millisecond, WaitMax: 10 * time.Millisecond}}
+	resp, err := client.Post(server.URL, "text/plain", bytes.NewBufferString("payload"))
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected 200, got %d", resp.StatusCode)
+	}
+	if attempts != 3 {
+		t.Fatalf("expected 3 attempts, got %d", attempts)
+	}
+}
+
+func TestRetryTransport_DoesNotRetryNonIdempotentServerErrors(t *testing.T) {
+	attempts := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	client := &http.Client{Transport: &RetryTransport{MaxRetries: 3, WaitMin: time.Millisecond, WaitMax: 10 * time.Millisecond}}
+
+	resp, err := client.Post(server.URL, "text/plain", bytes.NewBufferString("payload"))
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if resp.StatusCode != http.StatusServiceUnavailable || attempts != 1 {
+		t.Fatalf("expected a single 503 attempt, got %d attempts with status %d", attempts, resp.StatusCode)
+	}
+
+	attempts = 0
+	resp, err = client.Get(server.URL)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if resp.StatusCode != http.StatusServiceUnavailable || attempts != 4 {
+		t.Fatalf("expected 4 attempts, got %d attempts with status %d", attempts, resp.StatusCode)
+	}
+}
+
+func TestRetryTransport_Backoff(t *testing.T) {
+	transport := &RetryTransport{WaitMin: time.Second, WaitMax: 5 * time.Second}
+
+	expected := []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 5 * time.Second}
+	for attempt, want := range expected {
+		if got := transport.backoff(attempt, nil); got != want {
+			t.Errorf("attempt %d: expected %s, got %s", attempt, want, got)
+		}
+	}
+
+	if got := transport.backoff(1000, nil); got != 5*time.Second {
+		t.Errorf("expected the wait to be capped at 5s on overflow, got %s", got)
+	}
+
+	noWait := &RetryTransport{WaitMin: 0, WaitMax: 5 * time.Second}
+	for _, attempt := range []int{0, 3, 1000} {
+		if got := noWait.backoff(attempt, nil); got != 0 {
+			t.Errorf("attempt %d: expected no wait without a minimum, got %s", attempt, got)
+		}
+	}
+
+	resp := &http.Response{Header: http.Header{"Retry-After": []string{"3"}}}
+	if got := transport.backoff(0, resp); got != 3*time.Second {
+		t.Errorf("expected Retry-After of 3s to be honoured, got %s", got)
+	}
+
+	resp = &http.Response{Header: http.Header{"Retry-After": []string{"120"}}}
+	if got := transport.backoff(0, resp); got != 5*time.Second {
+		t.Errorf("expected Retry-After to be capped at 5s, got %s", got)
+	}
+}
@@ -0,0 +1,74 @@
+package transport
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+	"strings"
+)
+
+// TLSOptions describes the TLS settings of the transports. Certificates and keys can either
+// be given inline as PEM or as a path to a PEM file.
+type TLSOptions struct {
+	CACert             string
+	ClientCert         string
+	ClientKey          string
+	InsecureSkipVerify bool
+}
+
+// NewTLSConfig builds a tls.Config from the given options. The CA bundle is added on top
+// of the system trust store.
+func NewTLSConfig(opts TLSOptions) (*tls.Config, error) {
+	config := &tls.Config{
+		InsecureSkipVerify: opts.InsecureSkipVerify,
+	}
+
+	if opts.CACert != "" {
+		caCert, err := readPEM(opts.CACert)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CA certificate: %s", err)
+		}
+
+		pool, err := x509.SystemCertPool()
+		if err != nil || pool == nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(caCert) {
+			return nil, fmt.Errorf("no certificates found in CA certificate")
+		}
+		config.RootCAs = pool
+	}
+
+	if (opts.ClientCert == "") != (opts.ClientKey == "") {
+		return nil, fmt.Errorf("both client certificate and client key need to be specified")
+	}
+
+	if opts.ClientCert != "" {
+		clientCert, err := readPEM(opts.ClientCert)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read client certificate: %s", err)
+		}
+
+		clientKey, err := readPEM(opts.ClientKey)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read client key: %s", err)
+		}
+
+		certificate, err := tls.X509KeyPair(clientCert, clientKey)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load client certificate: %s", err)
+		}
+		config.Certificates = []tls.Certificate{certificate}
+	}
+
+	return config, nil
+}
+
+// readPEM returns inline PEM content as-is and otherwise treats the value as a file path.
+func readPEM(value string) ([]byte, error) {
+	if strings.HasPrefix(strings.TrimSpace(value), "-----BEGIN") {
+		return []byte(value), nil
+	}
+	return ioutil.ReadFile(value)
+}
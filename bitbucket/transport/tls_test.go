@@ -0,0 +1,74 @@
+package transport
+
+import (
+	"encoding/pem"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestNewTLSConfig_CACert(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	caCert := string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw}))
+
+	caFile := filepath.Join(t.TempDir(), "ca.pem")
+	if err := ioutil.WriteFile(caFile, []byte(caCert), 0600); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	for name, value := range map[string]string{"inline": caCert, "file": caFile} {
+		tlsConfig, err := NewTLSConfig(TLSOptions{CACert: value})
+		if err != nil {
+			t.Fatalf("%s: err: %s", name, err)
+		}
+
+		client := &http.Client{Transport: NewTransport(Options{TLSConfig: tlsConfig})}
+		if _, err := client.Get(server.URL); err != nil {
+			t.Fatalf("%s: err: %s", name, err)
+		}
+	}
+}
+
+func TestNewTLSConfig_UnknownAuthority(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	tlsConfig, err := NewTLSConfig(TLSOptions{})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	client := &http.Client{Transport: NewTransport(Options{TLSConfig: tlsConfig})}
+	if _, err := client.Get(server.URL); err == nil {
+		t.Fatal("expected certificate verification to fail")
+	}
+
+	tlsConfig, err = NewTLSConfig(TLSOptions{InsecureSkipVerify: true})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	client = &http.Client{Transport: NewTransport(Options{TLSConfig: tlsConfig})}
+	if _, err := client.Get(server.URL); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+}
+
+func TestNewTLSConfig_Errors(t *testing.T) {
+	if _, err := NewTLSConfig(TLSOptions{ClientCert: "cert.pem"}); err == nil {
+		t.Error("expected error when client key is missing")
+	}
+
+	if _, err := NewTLSConfig(TLSOptions{CACert: filepath.Join(os.TempDir(), "does-not-exist.pem")}); err == nil {
+		t.Error("expected error for missing CA file")
+	}
+
+	if _, err := NewTLSConfig(TLSOptions{CACert: "-----BEGIN CERTIFICATE-----\ninvalid\n-----END CERTIFICATE-----"}); err == nil {
+		t.Error("expected error for invalid CA certificate")
+	}
+}
@@ -0,0 +1,32 @@
+package transport
+
+import (
+	"crypto/tls"
+	"net/http"
+	"net/url"
+)
+
+// Options configures the base transport of an API client.
+type Options struct {
+	TLSConfig *tls.Config
+	Proxy     func(*http.Request) (*url.URL, error)
+	// MaxIdleConnsPerHost is the number of keep-alive connections kept open per host,
+	// 0 keeps the net/http default.
+	MaxIdleConnsPerHost int
+}
+
+// NewTransport returns a copy of the default transport using the given options.
+func NewTransport(opts Options) *http.Transport {
+	t := http.DefaultTransport.(*http.Transport).Clone()
+	t.TLSClientConfig = opts.TLSConfig
+	if opts.Proxy != nil {
+		t.Proxy = opts.Proxy
+	}
+	if opts.MaxIdleConnsPerHost > 0 {
+		t.MaxIdleConnsPerHost = opts.MaxIdleConnsPerHost
+		if t.MaxIdleConns < opts.MaxIdleConnsPerHost {
+			t.MaxIdleConns = opts.MaxIdleConnsPerHost
+		}
+	}
+	return t
+}
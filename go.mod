@@ -1,5 +1,8 @@
 module github.com/testOrgNataichi/terraform-provider-bitbucketserver
 
-require github.com/hashicorp/terraform v0.12.2
+require (
+	github.com/hashicorp/terraform v0.12.2
+	golang.org/x/net v0.0.0-20190502183928-7f726cade0ab
+)
 
 go 1.16